@@ -1,22 +1,29 @@
 package cern
 
+import (
+	"context"
+	"net/http"
+)
+
 type CernConfig interface {
-	GetLandbClient() (*LandbClient, error)
+	GetLandbClient(ctx context.Context) (*LandbClient, error)
 }
 
 //Config stores the information needed by the provider to work
 type config struct {
-	LdapServer    string
-	LandbEndpoint string
-	LandbUsername string
-	LandbPassword string
-	TeigiClient   *Teigi
-	RogerClient   *Roger
-	CertMgrClient *CertMgr
+	LdapServer      string
+	LandbEndpoint   string
+	LandbUsername   string
+	LandbPassword   string
+	LandbHTTPClient *http.Client
+	TeigiClient     *Teigi
+	RogerClient     *Roger
+	CertMgrClient   *CertMgr
+	Principal       string
 }
 
-func (c config) GetLandbClient() (*LandbClient, error) {
+func (c config) GetLandbClient(ctx context.Context) (*LandbClient, error) {
 	// This LanDB client is initialised with a token that should be valid for
 	// a few hours. A renovation mechanism has not been implemented yet.
-	return NewLandbClient(c.LandbEndpoint, c.LandbUsername, c.LandbPassword)
+	return NewLandbClient(ctx, c.LandbEndpoint, c.LandbUsername, c.LandbPassword, c.LandbHTTPClient)
 }
@@ -0,0 +1,146 @@
+package cern
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceLandbVMClusterOrphans() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceLandbVMClusterOrphansRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"vm_cluster_name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Name of the LanDB VM cluster to inspect",
+			},
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only consider the VMs whose name starts with this prefix",
+			},
+			"tag": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only consider the VMs registered with this tag",
+			},
+			"managed_names": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Names of the VMs managed elsewhere, which are never reported as orphans",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"orphans": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "VMs registered in the cluster that are not in 'managed_names'",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"device_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"tag": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"interfaces": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"name": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"ip_address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"ipv6_address": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceLandbVMClusterOrphansRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	clusterName := d.Get("vm_cluster_name").(string)
+	prefix := strings.ToUpper(d.Get("name_prefix").(string))
+	tag := d.Get("tag").(string)
+
+	// LanDB stores device names in upper case
+	managed := make(map[string]bool)
+	for _, name := range d.Get("managed_names").(*schema.Set).List() {
+		managed[strings.ToUpper(name.(string))] = true
+	}
+
+	log.Printf("[DEBUG] Listing devices of LanDB VM cluster %s", clusterName)
+	devices, err := landbClient.VMClusterGetDevices(ctx, clusterName)
+	if err != nil {
+		return diag.Errorf("Error listing devices of VM cluster %s: %s", clusterName, err)
+	}
+	sort.Strings(devices)
+
+	orphans := make([]map[string]interface{}, 0)
+	for _, device := range devices {
+		name := strings.ToUpper(device)
+		if managed[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		info, err := landbClient.GetDeviceInfo(ctx, device)
+		if err != nil {
+			return diag.Errorf("Error getting device info for %s: %s", device, err)
+		}
+		if tag != "" && info.Tag != tag {
+			continue
+		}
+
+		interfaces := make([]map[string]interface{}, 0, len(info.Interfaces))
+		for _, iface := range info.Interfaces {
+			interfaces = append(interfaces, map[string]interface{}{
+				"name":         iface.Name,
+				"ip_address":   iface.IPAddress,
+				"ipv6_address": iface.IPv6Address,
+			})
+		}
+		orphans = append(orphans, map[string]interface{}{
+			"device_name": device,
+			"tag":         info.Tag,
+			"interfaces":  interfaces,
+		})
+	}
+
+	d.SetId(clusterName)
+	if err := d.Set("orphans", orphans); err != nil {
+		return diag.Errorf("Unable to set orphans: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cern
+
+import (
+	"context"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// rogerStateSchema returns the computed attributes of a Roger state, as
+// filled by rogerStateAttributes
+func rogerStateSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"appstate": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Application state of the host",
+		},
+		"app_alarmed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether or not application alarms are enabled",
+		},
+		"hw_alarmed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether or not hardware alarms are enabled",
+		},
+		"nc_alarmed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether or not no contact alarms are enabled",
+		},
+		"os_alarmed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether or not operating system alarms are enabled",
+		},
+		"message": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Message attached to the state",
+		},
+		"expires": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Expiry of the state",
+		},
+		"expires_dt": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Expiry of the state, as a RFC3339 date",
+		},
+		"update_time": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Last time the state was updated",
+		},
+		"update_time_dt": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Last time the state was updated, as a date",
+		},
+		"updated_by": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Last user who updated the state",
+		},
+		"updated_by_puppet": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether or not the state was last updated by Puppet",
+		},
+	}
+}
+
+func dataSourceRoger() *schema.Resource {
+	s := rogerStateSchema()
+	s["hostname"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Required:    true,
+		Description: "Hostname to look the roger state up for",
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceRogerRead,
+		Schema:      s,
+	}
+}
+
+func dataSourceRogerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+	hostname := d.Get("hostname").(string)
+
+	log.Printf("[DEBUG] Creating roger read request for %s", hostname)
+	resp, err := client.Get(ctx, hostname)
+	if err != nil {
+		return diag.Errorf("Error reading roger state: %s", err)
+	}
+
+	d.SetId(client.CanonicalHostname(hostname))
+	for key, value := range rogerStateAttributes(resp) {
+		if err := d.Set(key, value); err != nil {
+			return diag.Errorf("Error setting %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
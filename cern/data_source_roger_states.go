@@ -0,0 +1,105 @@
+package cern
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceRogerStates() *schema.Resource {
+	state := rogerStateSchema()
+	state["hostname"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceRogerStatesRead,
+
+		Schema: map[string]*schema.Schema{
+			"appstate": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Application state the hosts must be in",
+			},
+
+			"hostname_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Regular expression the hostnames must match",
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+
+			"hostnames": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Sorted hostnames of the matching roger states",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"states": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Matching roger states, sorted by hostname",
+				Elem: &schema.Resource{
+					Schema: state,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceRogerStatesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+	appstate := d.Get("appstate").(string)
+	pattern := d.Get("hostname_regex").(string)
+
+	hostnameRegex, err := regexp.Compile(pattern)
+	if err != nil {
+		return diag.Errorf("Invalid hostname_regex: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating roger list request for %s appstate", appstate)
+	resps, err := client.List(ctx, appstate)
+	if err != nil {
+		return diag.Errorf("Error listing roger states: %s", err)
+	}
+	sort.Slice(resps, func(i, j int) bool {
+		return resps[i].Hostname < resps[j].Hostname
+	})
+
+	hostnames := make([]string, 0)
+	states := make([]interface{}, 0)
+	for i := range resps {
+		resp := &resps[i]
+		// The appstate is filtered again in case the endpoint ignores it
+		if appstate != "" && resp.AppState != appstate {
+			continue
+		}
+		if !hostnameRegex.MatchString(resp.Hostname) {
+			continue
+		}
+
+		state := rogerStateAttributes(resp)
+		state["hostname"] = resp.Hostname
+		hostnames = append(hostnames, resp.Hostname)
+		states = append(states, state)
+	}
+
+	d.SetId(appstate + "/" + pattern)
+	if err := d.Set("hostnames", hostnames); err != nil {
+		return diag.Errorf("Unable to set hostnames: %s", err)
+	}
+	if err := d.Set("states", states); err != nil {
+		return diag.Errorf("Unable to set states: %s", err)
+	}
+
+	return nil
+}
@@ -1,13 +1,16 @@
 package cern
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v3"
 )
 
 func dataSourceTeigiSecret() *schema.Resource {
@@ -19,7 +22,7 @@ func dataSourceTeigiSecret() *schema.Resource {
 				Type:          schema.TypeString,
 				Optional:      true,
 				Description:   "Host where the secret is located",
-				ConflictsWith: []string{"hostgroup", "service"},
+				ConflictsWith: []string{"service"},
 				ValidateFunc:  validation.StringDoesNotContainAny("/"),
 			},
 
@@ -27,7 +30,7 @@ func dataSourceTeigiSecret() *schema.Resource {
 				Type:          schema.TypeString,
 				Optional:      true,
 				Description:   "Hostgroup where the secret is located",
-				ConflictsWith: []string{"host", "service"},
+				ConflictsWith: []string{"service"},
 			},
 
 			"service": {
@@ -39,9 +42,92 @@ func dataSourceTeigiSecret() *schema.Resource {
 			},
 
 			"key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Description:  "Key name which to retrieve",
+				ExactlyOneOf: []string{"key", "keys"},
+			},
+
+			"keys": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				Description:   "Key names which to retrieve from the same entity, exposed in 'secrets'",
+				ConflictsWith: []string{"lookup_hierarchy"},
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"allow_missing": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Whether or not to return empty values instead of failing when the " +
+					"secret does not exist, leaving the missing keys out of 'secrets' when 'keys' is set",
+			},
+
+			"decode": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Format of the secret to parse into 'decoded': 'json' or 'yaml'",
+				ConflictsWith: []string{"keys"},
+				ValidateFunc:  validation.StringInSlice([]string{"json", "yaml"}, false),
+			},
+
+			"decoded": {
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+				Description: "Top level values of the secret document when 'decode' is set, " +
+					"with the nested objects and lists encoded as JSON",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"exists": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether or not the secret exists, or all of the keys when 'keys' is set",
+			},
+
+			"lookup_hierarchy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Look the secret up like Puppet does, trying the host first and " +
+					"then every hostgroup from the most specific one to the top level one",
+				ConflictsWith: []string{"service"},
+			},
+
+			"update_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Last time the secret was updated",
+			},
+
+			"update_time_str": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Key name which to retrieve",
+				Computed:    true,
+				Description: "Last time the secret was updated, in human readable form",
+			},
+
+			"updated_by": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Last user who updated the secret",
+			},
+
+			"matched_scope": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Scope where the secret was found",
+			},
+
+			"matched_entity": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Entity where the secret was found",
 			},
 
 			"base64_encoded": {
@@ -56,18 +142,28 @@ func dataSourceTeigiSecret() *schema.Resource {
 				Sensitive:   true,
 				Description: "Secret string retrieved from Teigi",
 			},
+
+			"secrets": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Secrets retrieved from Teigi when 'keys' is set, indexed by key name",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 		},
 	}
 }
 
 func getScopeAndEntity(d *schema.ResourceData) (string, string, error) {
+	return scopeAndEntity(d.Get("host").(string), d.Get("hostgroup").(string), d.Get("service").(string))
+}
+
+func scopeAndEntity(host string, hostgroup string, service string) (string, string, error) {
 	var scope string
 	var entity string
 
-	hostgroup := d.Get("hostgroup").(string)
-	host := d.Get("host").(string)
-	service := d.Get("service").(string)
-
 	if hostgroup != "" {
 		scope = "hostgroup"
 		entity = SerializeHostgroup(hostgroup)
@@ -84,24 +180,77 @@ func getScopeAndEntity(d *schema.ResourceData) (string, string, error) {
 	return scope, entity, nil
 }
 
+// lookupHierarchy returns the scopes and entities where a secret is looked
+// up, in the same order as Puppet does
+func lookupHierarchy(d *schema.ResourceData) ([][2]string, error) {
+	host := d.Get("host").(string)
+	hostgroup := d.Get("hostgroup").(string)
+
+	if !d.Get("lookup_hierarchy").(bool) {
+		if host != "" && hostgroup != "" {
+			return nil, fmt.Errorf("'host' and 'hostgroup' can only be set together with 'lookup_hierarchy'")
+		}
+		scope, entity, err := getScopeAndEntity(d)
+		if err != nil {
+			return nil, err
+		}
+		return [][2]string{{scope, entity}}, nil
+	}
+
+	if host == "" && hostgroup == "" {
+		return nil, fmt.Errorf("one of the variable 'hostgroup' or 'host' should be set")
+	}
+
+	var hierarchy [][2]string
+	if host != "" {
+		hierarchy = append(hierarchy, [2]string{"host", host})
+	}
+	if hostgroup != "" {
+		for _, entity := range HostgroupHierarchy(hostgroup) {
+			hierarchy = append(hierarchy, [2]string{"hostgroup", entity})
+		}
+	}
+	return hierarchy, nil
+}
+
 func dataSourceTeigiSecretRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*config).TeigiClient
 
-	scope, entity, err := getScopeAndEntity(d)
+	if _, ok := d.GetOk("keys"); ok {
+		return dataSourceTeigiSecretReadKeys(ctx, d, client)
+	}
+
+	hierarchy, err := lookupHierarchy(d)
 	if err != nil {
 		return diag.Errorf("Error getting scope and entity: %s", err)
 	}
 
 	key := d.Get("key").(string)
-	log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
-		scope, entity, key)
+	var scope, entity string
+	var secretResponse *SecretResponse
+	for _, level := range hierarchy {
+		scope, entity = level[0], level[1]
+		log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+			scope, entity, key)
 
-	secretResponse, err := client.Get(ctx, scope, entity, key)
-	if err != nil {
+		secretResponse, err = client.Get(ctx, scope, entity, key)
+		if err == nil || !IsNotFound(err) {
+			break
+		}
+	}
+	exists := err == nil
+	if IsNotFound(err) && d.Get("allow_missing").(bool) {
+		log.Printf("[DEBUG] Teigi secret %s not found, returning empty values", key)
+		scope, entity = hierarchy[0][0], hierarchy[0][1]
+		secretResponse = &SecretResponse{}
+	} else if err != nil {
 		return diag.Errorf("Unable to get secret: %s", err)
 	}
 
 	d.SetId(scope + "/" + entity + "/" + key)
+	if err := d.Set("exists", exists); err != nil {
+		return diag.Errorf("Unable to set exists: %s", err)
+	}
 	if err = d.Set("secret", secretResponse.Secret); err != nil {
 		return diag.Errorf("Unable to set secret: %s", err)
 	}
@@ -110,5 +259,126 @@ func dataSourceTeigiSecretRead(ctx context.Context, d *schema.ResourceData, meta
 	if err := d.Set("base64_encoded", base64_encoded); err != nil {
 		return diag.Errorf("Unable to set base64_encoded: %s", err)
 	}
+
+	decoded := make(map[string]interface{})
+	if format, ok := d.GetOk("decode"); ok && exists {
+		document := []byte(secretResponse.Secret)
+		if base64_encoded {
+			if document, err = decodeBase64(secretResponse.Secret); err != nil {
+				return diag.Errorf("Unable to decode base64 secret: %s", err)
+			}
+		}
+		if decoded, err = decodeSecretDocument(document, format.(string)); err != nil {
+			return diag.Errorf("Unable to decode secret as %s: %s", format, err)
+		}
+	}
+	if err := d.Set("decoded", decoded); err != nil {
+		return diag.Errorf("Unable to set decoded: %s", err)
+	}
+	if err := d.Set("update_time", secretResponse.UpdateTime); err != nil {
+		return diag.Errorf("Unable to set update_time: %s", err)
+	}
+	if err := d.Set("update_time_str", secretResponse.UpdateTimeStr); err != nil {
+		return diag.Errorf("Unable to set update_time_str: %s", err)
+	}
+	if err := d.Set("updated_by", secretResponse.UpdatedBy); err != nil {
+		return diag.Errorf("Unable to set updated_by: %s", err)
+	}
+	if !exists {
+		scope, entity = "", ""
+	}
+	if err := d.Set("matched_scope", scope); err != nil {
+		return diag.Errorf("Unable to set matched_scope: %s", err)
+	}
+	if err := d.Set("matched_entity", entity); err != nil {
+		return diag.Errorf("Unable to set matched_entity: %s", err)
+	}
+	return nil
+}
+
+// decodeSecretDocument parses a JSON or YAML object into a map of strings.
+// Scalars are kept as they are written, while nested objects and lists are
+// encoded as JSON so that they can be passed to jsondecode.
+func decodeSecretDocument(document []byte, format string) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(document))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(document, &values); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+
+	decoded := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+			decoded[key] = ""
+		case string:
+			decoded[key] = v
+		case map[string]interface{}, []interface{}:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode %s: %s", key, err)
+			}
+			decoded[key] = string(encoded)
+		default:
+			decoded[key] = fmt.Sprint(v)
+		}
+	}
+	return decoded, nil
+}
+
+// dataSourceTeigiSecretReadKeys fetches every key listed in 'keys' from the
+// same entity
+func dataSourceTeigiSecretReadKeys(ctx context.Context, d *schema.ResourceData, client *Teigi) diag.Diagnostics {
+	if d.Get("host").(string) != "" && d.Get("hostgroup").(string) != "" {
+		return diag.Errorf("'host' and 'hostgroup' can only be set together with 'lookup_hierarchy'")
+	}
+
+	scope, entity, err := getScopeAndEntity(d)
+	if err != nil {
+		return diag.Errorf("Error getting scope and entity: %s", err)
+	}
+
+	exists := true
+	secrets := make(map[string]interface{})
+	for _, key := range d.Get("keys").([]interface{}) {
+		key := key.(string)
+		log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+			scope, entity, key)
+
+		secretResponse, err := client.Get(ctx, scope, entity, key)
+		if IsNotFound(err) && d.Get("allow_missing").(bool) {
+			exists = false
+			continue
+		} else if err != nil {
+			return diag.Errorf("Unable to get secret %s: %s", key, err)
+		}
+		secrets[key] = secretResponse.Secret
+	}
+
+	d.SetId(scope + "/" + entity)
+	if err := d.Set("secrets", secrets); err != nil {
+		return diag.Errorf("Unable to set secrets: %s", err)
+	}
+	if err := d.Set("exists", exists); err != nil {
+		return diag.Errorf("Unable to set exists: %s", err)
+	}
+	if exists {
+		if err := d.Set("matched_scope", scope); err != nil {
+			return diag.Errorf("Unable to set matched_scope: %s", err)
+		}
+		if err := d.Set("matched_entity", entity); err != nil {
+			return diag.Errorf("Unable to set matched_entity: %s", err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,144 @@
+package cern
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceTeigiSecrets() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceTeigiSecretsRead,
+
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Host where the secrets are located",
+				ConflictsWith: []string{"hostgroup", "service"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"hostgroup": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Hostgroup where the secrets are located",
+				ConflictsWith: []string{"host", "service"},
+			},
+
+			"service": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Service where the secrets are located",
+				ConflictsWith: []string{"host", "hostgroup"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"include_secrets": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether or not to retrieve the secret values as well",
+			},
+
+			"keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Key names of the secrets stored under the entity",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"secrets": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Secrets stored under the entity",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"key": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"base64_encoded": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"update_time": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"update_time_str": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"updated_by": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"secret": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Sensitive:   true,
+							Description: "Only set when 'include_secrets' is true",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceTeigiSecretsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+
+	scope, entity, err := getScopeAndEntity(d)
+	if err != nil {
+		return diag.Errorf("Error getting scope and entity: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi list request for %s scope and %s entity", scope, entity)
+	keys, err := client.List(ctx, scope, entity)
+	if err != nil {
+		return diag.Errorf("Unable to list secrets: %s", err)
+	}
+	sort.Strings(keys)
+
+	includeSecrets := d.Get("include_secrets").(bool)
+	secrets := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		// The list endpoint only returns the key names, the metadata comes
+		// along with the secret itself
+		secretResponse, err := client.Get(ctx, scope, entity, key)
+		if err != nil {
+			return diag.Errorf("Unable to get secret %s: %s", key, err)
+		}
+
+		secret := map[string]interface{}{
+			"key":             key,
+			"base64_encoded":  secretResponse.Encoding == "b64",
+			"update_time":     secretResponse.UpdateTime,
+			"update_time_str": secretResponse.UpdateTimeStr,
+			"updated_by":      secretResponse.UpdatedBy,
+			"secret":          "",
+		}
+		if includeSecrets {
+			secret["secret"] = secretResponse.Secret
+		}
+		secrets = append(secrets, secret)
+	}
+
+	d.SetId(scope + "/" + entity)
+	if err := d.Set("keys", keys); err != nil {
+		return diag.Errorf("Unable to set keys: %s", err)
+	}
+	if err := d.Set("secrets", secrets); err != nil {
+		return diag.Errorf("Unable to set secrets: %s", err)
+	}
+
+	return nil
+}
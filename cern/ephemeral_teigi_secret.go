@@ -0,0 +1,147 @@
+package cern
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ephemeralTeigiSecret reads a Teigi secret that is never persisted in the
+// plan or the state. It requires Terraform >= 1.10.
+type ephemeralTeigiSecret struct {
+	client *Teigi
+}
+
+type ephemeralTeigiSecretModel struct {
+	Host          types.String `tfsdk:"host"`
+	Hostgroup     types.String `tfsdk:"hostgroup"`
+	Service       types.String `tfsdk:"service"`
+	Key           types.String `tfsdk:"key"`
+	Base64Encoded types.Bool   `tfsdk:"base64_encoded"`
+	Secret        types.String `tfsdk:"secret"`
+}
+
+func newEphemeralTeigiSecret() ephemeral.EphemeralResource {
+	return &ephemeralTeigiSecret{}
+}
+
+func (e *ephemeralTeigiSecret) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_teigi_secret"
+}
+
+func (e *ephemeralTeigiSecret) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				Optional:    true,
+				Description: "Host where the secret is located",
+			},
+			"hostgroup": schema.StringAttribute{
+				Optional:    true,
+				Description: "Hostgroup where the secret is located",
+			},
+			"service": schema.StringAttribute{
+				Optional:    true,
+				Description: "Service where the secret is located",
+			},
+			"key": schema.StringAttribute{
+				Required:    true,
+				Description: "Key name which to retrieve",
+			},
+			"base64_encoded": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether or not the secret is base64 encoded",
+			},
+			"secret": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Secret string retrieved from Teigi",
+			},
+		},
+	}
+}
+
+// ValidateConfig rejects the same combinations of 'host', 'hostgroup' and
+// 'service' as the cern_teigi_secret data source, without 'lookup_hierarchy'
+func (e *ephemeralTeigiSecret) ValidateConfig(ctx context.Context, req ephemeral.ValidateConfigRequest, resp *ephemeral.ValidateConfigResponse) {
+	var data ephemeralTeigiSecretModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	entities := []struct {
+		name  string
+		value types.String
+	}{
+		{"host", data.Host},
+		{"hostgroup", data.Hostgroup},
+		{"service", data.Service},
+	}
+
+	var set []string
+	for _, entity := range entities {
+		if entity.value.IsNull() || entity.value.IsUnknown() {
+			continue
+		}
+		set = append(set, entity.name)
+		if entity.name != "hostgroup" && strings.Contains(entity.value.ValueString(), "/") {
+			resp.Diagnostics.AddAttributeError(path.Root(entity.name), "Invalid attribute value",
+				fmt.Sprintf("'%s' must not contain '/', got %q", entity.name, entity.value.ValueString()))
+		}
+	}
+	if len(set) > 1 {
+		resp.Diagnostics.AddAttributeError(path.Root(set[1]), "Conflicting attributes",
+			fmt.Sprintf("'%s' conflicts with '%s', only one of 'host', 'hostgroup' and 'service' can be set",
+				set[1], set[0]))
+	}
+}
+
+func (e *ephemeralTeigiSecret) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	// ProviderData is nil until the provider has been configured
+	if req.ProviderData == nil {
+		return
+	}
+
+	config, ok := req.ProviderData.(*config)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data",
+			fmt.Sprintf("Expected *config, got %T", req.ProviderData))
+		return
+	}
+	e.client = config.TeigiClient
+}
+
+func (e *ephemeralTeigiSecret) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data ephemeralTeigiSecretModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	scope, entity, err := scopeAndEntity(data.Host.ValueString(), data.Hostgroup.ValueString(), data.Service.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting scope and entity", err.Error())
+		return
+	}
+
+	key := data.Key.ValueString()
+	log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+		scope, entity, key)
+
+	secretResponse, err := e.client.Get(ctx, scope, entity, key)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to get secret", err.Error())
+		return
+	}
+
+	data.Secret = types.StringValue(secretResponse.Secret)
+	data.Base64Encoded = types.BoolValue(secretResponse.Encoding == "b64")
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
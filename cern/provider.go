@@ -1,7 +1,16 @@
 package cern
 
 import (
+	"log"
+	"time"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+const (
+	defaultTeigiEndpoint   = "https://woger.cern.ch:8201"
+	defaultTeigiHostDomain = "cern.ch"
 )
 
 // Provider defines the schema of the CERN provider seen by Terraform
@@ -30,13 +39,50 @@ func Provider() *schema.Provider {
 				Sensitive:   true,
 				DefaultFunc: schema.EnvDefaultFunc("CERN_LANDB_PASSWORD", ""),
 			},
+			"landb_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("CERN_LANDB_TIMEOUT", 60),
+				Description:  "Timeout in seconds of every request to LanDB",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
 			"teigi_endpoint": {
 				Type:        schema.TypeString,
 				Required:    false,
 				Optional:    true,
-				DefaultFunc: schema.EnvDefaultFunc("CERN_TEIGI_ENDPOINT", "https://woger.cern.ch:8201"),
+				DefaultFunc: schema.EnvDefaultFunc("CERN_TEIGI_ENDPOINT", defaultTeigiEndpoint),
 				Description: "Teigi API url that we can use",
 			},
+			"kerberos_principal": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CERN_KERBEROS_PRINCIPAL", ""),
+				Description: "Kerberos principal used by the provider, detected from the credentials cache if not set",
+			},
+			"teigi_host_domain": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CERN_TEIGI_HOST_DOMAIN", defaultTeigiHostDomain),
+				Description: "Domain appended to the short host names used in Teigi and Roger",
+			},
+			"teigi_host_verification": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("CERN_TEIGI_HOST_VERIFICATION", HostVerificationAlways),
+				Description: "When to check that the Teigi hosts exist in the DNS: 'always', " +
+					"'plan' to only check the hosts known at plan time, or 'never'",
+				ValidateFunc: validation.StringInSlice([]string{
+					HostVerificationAlways, HostVerificationPlan, HostVerificationNever,
+				}, false),
+			},
+			"roger_appstates": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Application states accepted by Roger, replacing the built-in list",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 			"certmgr_endpoint": {
 				Type:        schema.TypeString,
 				Required:    false,
@@ -46,16 +92,24 @@ func Provider() *schema.Provider {
 			},
 		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"cern_egroup":       dataSourceCernEgroup(),
-			"cern_teigi_secret": dataSourceTeigiSecret(),
+			"cern_egroup":                   dataSourceCernEgroup(),
+			"cern_landb_vm_cluster_orphans": dataSourceLandbVMClusterOrphans(),
+			"cern_roger":                    dataSourceRoger(),
+			"cern_roger_states":             dataSourceRogerStates(),
+			"cern_teigi_secret":             dataSourceTeigiSecret(),
+			"cern_teigi_secrets":            dataSourceTeigiSecrets(),
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"cern_landb_vm":           landbVMResource(),
-			"cern_landb_vm_card":      landbVMCardResource(),
-			"cern_landb_vm_interface": landbVMInterfaceResource(),
-			"cern_roger":              rogerResource(),
-			"cern_certmgr":            certMgrResource(),
-			"cern_teigi_secret":       resourceTeigiSecret(),
+			"cern_landb_vm":               landbVMResource(),
+			"cern_landb_vm_card":          landbVMCardResource(),
+			"cern_landb_vm_interface":     landbVMInterfaceResource(),
+			"cern_roger":                  rogerResource(),
+			"cern_roger_bulk":             rogerBulkResource(),
+			"cern_certmgr":                certMgrResource(),
+			"cern_teigi_secret":           resourceTeigiSecret(),
+			"cern_teigi_generated_secret": resourceTeigiGeneratedSecret(),
+			"cern_teigi_secret_set":       resourceTeigiSecretSet(),
+			"cern_teigi_secret_copy":      resourceTeigiSecretCopy(),
 		},
 		ConfigureFunc: providerConfigure,
 	}
@@ -63,7 +117,11 @@ func Provider() *schema.Provider {
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 	// Teigi client
-	teigiClient, err := NewTeigiClient(d.Get("teigi_endpoint").(string))
+	hosts, err := NewHostNormalizer(d.Get("teigi_host_domain").(string), d.Get("teigi_host_verification").(string))
+	if err != nil {
+		return nil, err
+	}
+	teigiClient, err := NewTeigiClient(d.Get("teigi_endpoint").(string), hosts)
 	if err != nil {
 		return nil, err
 	}
@@ -73,21 +131,37 @@ func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 		return nil, err
 	}
 
-	// Roger client
-	rogerClient, err := NewRogerClient(d.Get("teigi_endpoint").(string))
+	// Roger client, sharing the host names normalisation of Teigi
+	var appStates []string
+	for _, appState := range d.Get("roger_appstates").([]interface{}) {
+		appStates = append(appStates, appState.(string))
+	}
+	rogerClient, err := NewRogerClient(d.Get("teigi_endpoint").(string), appStates, hosts)
 	if err != nil {
 		return nil, err
 	}
 
+	// Principal used to tell changes made by the provider apart from the
+	// ones made by someone else
+	principal := d.Get("kerberos_principal").(string)
+	if principal == "" {
+		principal, err = kerberosPrincipal()
+		if err != nil {
+			log.Printf("[WARN] Unable to detect the Kerberos principal: %s", err)
+		}
+	}
+
 	// Initialise Terraform provider configuration
 	config := &config{
-		LdapServer:    d.Get("ldap_server").(string),
-		LandbEndpoint: d.Get("landb_endpoint").(string),
-		LandbUsername: d.Get("landb_username").(string),
-		LandbPassword: d.Get("landb_password").(string),
-		TeigiClient:   teigiClient,
-		RogerClient:   rogerClient,
-		CertMgrClient: certMgrClient,
+		LdapServer:      d.Get("ldap_server").(string),
+		LandbEndpoint:   d.Get("landb_endpoint").(string),
+		LandbUsername:   d.Get("landb_username").(string),
+		LandbPassword:   d.Get("landb_password").(string),
+		LandbHTTPClient: NewLandbHTTPClient(time.Duration(d.Get("landb_timeout").(int)) * time.Second),
+		TeigiClient:     teigiClient,
+		RogerClient:     rogerClient,
+		CertMgrClient:   certMgrClient,
+		Principal:       principal,
 	}
 
 	return config, nil
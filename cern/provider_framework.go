@@ -0,0 +1,147 @@
+package cern
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// frameworkProvider serves the features that the SDKv2 provider cannot
+// implement, like ephemeral resources. It is muxed with the SDKv2 provider,
+// so its schema must be identical to the one defined in Provider().
+type frameworkProvider struct{}
+
+type frameworkProviderModel struct {
+	LdapServer            types.String `tfsdk:"ldap_server"`
+	LandbEndpoint         types.String `tfsdk:"landb_endpoint"`
+	LandbUsername         types.String `tfsdk:"landb_username"`
+	LandbPassword         types.String `tfsdk:"landb_password"`
+	LandbTimeout          types.Int64  `tfsdk:"landb_timeout"`
+	TeigiEndpoint         types.String `tfsdk:"teigi_endpoint"`
+	CertMgrEndpoint       types.String `tfsdk:"certmgr_endpoint"`
+	KerberosPrincipal     types.String `tfsdk:"kerberos_principal"`
+	TeigiHostDomain       types.String `tfsdk:"teigi_host_domain"`
+	TeigiHostVerification types.String `tfsdk:"teigi_host_verification"`
+	RogerAppStates        types.List   `tfsdk:"roger_appstates"`
+}
+
+// NewFrameworkProvider returns the terraform-plugin-framework part of the
+// CERN provider
+func NewFrameworkProvider() provider.Provider {
+	return &frameworkProvider{}
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "cern"
+}
+
+func (p *frameworkProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"ldap_server": schema.StringAttribute{
+				Optional: true,
+			},
+			"landb_endpoint": schema.StringAttribute{
+				Optional: true,
+			},
+			"landb_username": schema.StringAttribute{
+				Optional: true,
+			},
+			"landb_password": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+			},
+			"landb_timeout": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Timeout in seconds of every request to LanDB",
+			},
+			"teigi_endpoint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Teigi API url that we can use",
+			},
+			"certmgr_endpoint": schema.StringAttribute{
+				Optional:    true,
+				Description: "Certmgr API url that we can use",
+			},
+			"teigi_host_domain": schema.StringAttribute{
+				Optional:    true,
+				Description: "Domain appended to the short host names used in Teigi and Roger",
+			},
+			"teigi_host_verification": schema.StringAttribute{
+				Optional: true,
+				Description: "When to check that the Teigi hosts exist in the DNS: 'always', " +
+					"'plan' to only check the hosts known at plan time, or 'never'",
+			},
+			"roger_appstates": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Application states accepted by Roger, replacing the built-in list",
+			},
+			"kerberos_principal": schema.StringAttribute{
+				Optional:    true,
+				Description: "Kerberos principal used by the provider, detected from the credentials cache if not set",
+			},
+		},
+	}
+}
+
+func (p *frameworkProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var data frameworkProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Mirror the defaults of the SDKv2 provider
+	teigiEndpoint := stringWithDefault(data.TeigiEndpoint, "CERN_TEIGI_ENDPOINT", defaultTeigiEndpoint)
+	hostDomain := stringWithDefault(data.TeigiHostDomain, "CERN_TEIGI_HOST_DOMAIN", defaultTeigiHostDomain)
+	hostVerification := stringWithDefault(data.TeigiHostVerification, "CERN_TEIGI_HOST_VERIFICATION", HostVerificationAlways)
+
+	hosts, err := NewHostNormalizer(hostDomain, hostVerification)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Teigi host settings", err.Error())
+		return
+	}
+
+	teigiClient, err := NewTeigiClient(teigiEndpoint, hosts)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to create Teigi client", err.Error())
+		return
+	}
+
+	config := &config{
+		TeigiClient: teigiClient,
+	}
+	resp.EphemeralResourceData = config
+}
+
+// stringWithDefault behaves like schema.EnvDefaultFunc for the framework
+func stringWithDefault(value types.String, env string, defaultValue string) string {
+	if !value.IsNull() {
+		return value.ValueString()
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		newEphemeralTeigiSecret,
+	}
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return nil
+}
@@ -2,7 +2,10 @@ package cern
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -12,11 +15,22 @@ func certMgrResource() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceCertMgrCreate,
 		ReadContext:   resourceCertMgrRead,
+		UpdateContext: resourceCertMgrUpdate,
 		DeleteContext: resourceCertMgrDelete,
+		CustomizeDiff: resourceCertMgrCustomizeDiff,
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
 
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    certMgrResourceV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: certMgrStateUpgradeV0,
+			},
+		},
+
 		Schema: map[string]*schema.Schema{
 			"hostname": {
 				Type:        schema.TypeString,
@@ -30,6 +44,28 @@ func certMgrResource() *schema.Resource {
 				ForceNew: true,
 				Description: "This is a workaround to stage a " +
 					"new certificate whenever the associated server is recreated/rebuilt.",
+				Deprecated:       "Use triggers = { vm_tag = ... } instead",
+				ConflictsWith:    []string{"triggers"},
+				DiffSuppressFunc: suppressMigratedVMTag,
+			},
+			"triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Description: "Arbitrary values that stage a new certificate when changed, " +
+					"like the id of the associated server",
+				ConflictsWith: []string{"vm_tag"},
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"renew_before": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Stage a new certificate when the end of the current one is " +
+					"closer than this duration, like 720h",
+				ValidateFunc: validateDuration,
 			},
 			"id_cert": {
 				Type:     schema.TypeInt,
@@ -50,6 +86,94 @@ func certMgrResource() *schema.Resource {
 		},
 	}
 }
+
+// certMgrResourceV0 is the schema of cern_certmgr before 'triggers'
+// superseded 'vm_tag'
+func certMgrResourceV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"hostname": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vm_tag": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"id_cert": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"requestor": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"start": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"end": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// certMgrStateUpgradeV0 records 'vm_tag' in 'triggers', so that moving it
+// there in the configuration does not stage a new certificate
+func certMgrStateUpgradeV0(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	if vmTag, ok := rawState["vm_tag"].(string); ok && vmTag != "" {
+		rawState["triggers"] = map[string]interface{}{
+			"vm_tag": vmTag,
+		}
+	}
+	return rawState, nil
+}
+
+// suppressMigratedVMTag hides the removal of 'vm_tag' once its value is set
+// in 'triggers' instead
+func suppressMigratedVMTag(k, old, new string, d *schema.ResourceData) bool {
+	if new != "" || old == "" {
+		return false
+	}
+	triggers := d.Get("triggers").(map[string]interface{})
+	return triggers["vm_tag"] == old
+}
+
+func validateDuration(v interface{}, k string) ([]string, []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// resourceCertMgrCustomizeDiff stages a new certificate when the current one
+// ends within the 'renew_before' window
+func resourceCertMgrCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	renewBefore := d.Get("renew_before").(string)
+	if d.Id() == "" || renewBefore == "" {
+		return nil
+	}
+
+	window, err := time.ParseDuration(renewBefore)
+	if err != nil {
+		return fmt.Errorf("Invalid renew_before: %s", err)
+	}
+	end, ok := CertMgrResponse{End: d.Get("end").(string)}.EndTime()
+	if !ok || time.Until(end) > window {
+		return nil
+	}
+
+	log.Printf("[DEBUG] Certificate of %s ends on %s, staging a new one", d.Get("hostname"), end)
+	if err := d.SetNewComputed("id_cert"); err != nil {
+		return err
+	}
+	return d.ForceNew("id_cert")
+}
+
 func resourceCertMgrCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*config).CertMgrClient
 	hostname := d.Get("hostname").(string)
@@ -62,6 +186,14 @@ func resourceCertMgrCreate(ctx context.Context, d *schema.ResourceData, meta int
 
 	d.SetId(hostname)
 
+	// The deprecated vm_tag is recorded as a trigger, like the state upgrade
+	// does, so that moving it to triggers does not stage a new certificate
+	if vmTag := d.Get("vm_tag").(string); vmTag != "" {
+		if err := d.Set("triggers", map[string]interface{}{"vm_tag": vmTag}); err != nil {
+			return diag.Errorf("Error setting triggers: %s", err)
+		}
+	}
+
 	if err := d.Set("id_cert", resp.Id); err != nil {
 		return diag.Errorf("Error setting id: %s", err)
 	}
@@ -79,12 +211,79 @@ func resourceCertMgrCreate(ctx context.Context, d *schema.ResourceData, meta int
 }
 
 func resourceCertMgrRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	if err := d.Set("hostname", d.Id()); err != nil {
+	client := meta.(*config).CertMgrClient
+	hostname := d.Id()
+
+	// The staging is looked up by id, or by hostname when imported
+	var resp *CertMgrResponse
+	if id := d.Get("id_cert").(int); id != 0 {
+		log.Printf("[DEBUG] Creating CertMgr read request for %d", id)
+		var err error
+		resp, err = client.Get(ctx, id)
+		if err != nil {
+			return diag.FromErr(CheckDeleted(d, "Error reading staged certificate", err))
+		}
+	} else {
+		log.Printf("[DEBUG] Creating CertMgr list request for %s", hostname)
+		staged, err := client.List(ctx, hostname)
+		if err != nil {
+			return diag.Errorf("Error listing staged certificates: %s", err)
+		}
+		for i := range staged {
+			if resp == nil || staged[i].Id > resp.Id {
+				resp = &staged[i]
+			}
+		}
+	}
+
+	// A staging that was consumed, revoked or expired is gone, so that a new
+	// certificate is staged
+	if resp == nil || resp.Expired(time.Now()) {
+		log.Printf("[WARN] Staged certificate of %s is gone, removing it from state", hostname)
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("hostname", hostname); err != nil {
 		return diag.Errorf("Unable to set hostname: %s", err)
 	}
+	if err := d.Set("id_cert", resp.Id); err != nil {
+		return diag.Errorf("Error setting id: %s", err)
+	}
+	if err := d.Set("requestor", resp.Requestor); err != nil {
+		return diag.Errorf("Error setting requestor: %s", err)
+	}
+	if err := d.Set("start", resp.Start); err != nil {
+		return diag.Errorf("Error setting start: %s", err)
+	}
+	if err := d.Set("end", resp.End); err != nil {
+		return diag.Errorf("Error setting end: %s", err)
+	}
 	return nil
 }
 
+// resourceCertMgrUpdate only records the new 'renew_before', every other
+// change stages a new certificate
+func resourceCertMgrUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	return resourceCertMgrRead(ctx, d, meta)
+}
+
 func resourceCertMgrDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).CertMgrClient
+	id := d.Get("id_cert").(int)
+	if id == 0 {
+		return nil
+	}
+
+	// A certificate that was already consumed is gone as well
+	log.Printf("[DEBUG] Creating CertMgr unstage request for %d", id)
+	err := client.Unstage(ctx, id)
+	if hasStatusCode(err, http.StatusGone) {
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(CheckDeleted(d, "Error unstaging certificate", err))
+	}
+
 	return nil
 }
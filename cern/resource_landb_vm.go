@@ -2,8 +2,9 @@ package cern
 
 import (
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -12,12 +13,19 @@ func landbVMResource() *schema.Resource {
 
 		SchemaVersion: 1,
 
-		Read:   landbVMResourceRead,
-		Create: landbVMResourceCreate,
-		Update: landbVMResourceUpdate,
-		Delete: landbVMResourceDelete,
+		ReadContext:   landbVMResourceRead,
+		CreateContext: landbVMResourceCreate,
+		UpdateContext: landbVMResourceUpdate,
+		DeleteContext: landbVMResourceDelete,
 		Importer: &schema.ResourceImporter{
-			State: landbVMResourceImport,
+			StateContext: landbVMResourceImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -91,10 +99,10 @@ func landbVMResource() *schema.Resource {
 	}
 }
 
-func landbVMResourceCreate(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMResourceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 	operatingSystem := d.Get("operating_system").(map[string]interface{})
 	location := d.Get("location").(map[string]interface{})
@@ -138,20 +146,20 @@ func landbVMResourceCreate(d *schema.ResourceData, meta interface{}) error {
 	}
 	createOptions := VMCreateOptions{}
 
-	done, err := landbClient.VMCreate(context.TODO(), deviceInput, createOptions)
+	done, err := landbClient.VMCreate(ctx, deviceInput, createOptions)
 	if err != nil || !done {
-		return fmt.Errorf("error creating VM %s: %s", d.Get("device_name").(string), err)
+		return diag.Errorf("error creating VM %s: %s", d.Get("device_name").(string), err)
 	}
 
 	d.SetId(deviceInput.DeviceName)
 	return nil
 }
 
-func landbVMResourceUpdate(d *schema.ResourceData, meta interface{}) error {
+func landbVMResourceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	return nil
 }
 
-func landbVMResourceRead(d *schema.ResourceData, meta interface{}) error {
+func landbVMResourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	// When the resource is in the state, this call allows to read the
 	// remote API and update the values on the local state.
 	//
@@ -161,19 +169,19 @@ func landbVMResourceRead(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func landbVMResourceDelete(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMResourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
-	done, err := landbClient.VMDestroy(context.TODO(), d.Get("device_name").(string))
+	done, err := landbClient.VMDestroy(ctx, d.Get("device_name").(string))
 	if err != nil || !done {
-		return fmt.Errorf("error deleting VM %s: %s", d.Get("device_name").(string), err)
+		return diag.Errorf("error deleting VM %s: %s", d.Get("device_name").(string), err)
 	}
 	return nil
 }
 
-func landbVMResourceImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+func landbVMResourceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	return []*schema.ResourceData{d}, nil
 }
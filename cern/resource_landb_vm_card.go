@@ -2,8 +2,9 @@ package cern
 
 import (
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -12,11 +13,17 @@ func landbVMCardResource() *schema.Resource {
 
 		SchemaVersion: 1,
 
-		Read:   landbVMCardResourceRead,
-		Create: landbVMCardResourceCreate,
-		Delete: landbVMCardResourceDelete,
+		ReadContext:   landbVMCardResourceRead,
+		CreateContext: landbVMCardResourceCreate,
+		DeleteContext: landbVMCardResourceDelete,
 		Importer: &schema.ResourceImporter{
-			State: landbVMCardResourceImport,
+			StateContext: landbVMCardResourceImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -42,10 +49,10 @@ func landbVMCardResource() *schema.Resource {
 	}
 }
 
-func landbVMCardResourceCreate(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMCardResourceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	interfaceCard := InterfaceCard{
@@ -53,23 +60,23 @@ func landbVMCardResourceCreate(d *schema.ResourceData, meta interface{}) error {
 		CardType:        d.Get("card_type").(string),
 	}
 
-	hwAddr, err := landbClient.VMAddCard(context.TODO(), d.Get("vm_name").(string), interfaceCard)
+	hwAddr, err := landbClient.VMAddCard(ctx, d.Get("vm_name").(string), interfaceCard)
 	if err != nil || hwAddr != interfaceCard.HardwareAddress {
-		return fmt.Errorf("error creating VM card %s: %s", d.Get("vm_name").(string), err)
+		return diag.Errorf("error creating VM card %s: %s", d.Get("vm_name").(string), err)
 	}
 	d.SetId(hwAddr)
 	return nil
 }
 
-func landbVMCardResourceDelete(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMCardResourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
-	done, err := landbClient.VMRemoveCard(context.TODO(), d.Get("vm_name").(string), d.Get("hardware_address").(string))
+	done, err := landbClient.VMRemoveCard(ctx, d.Get("vm_name").(string), d.Get("hardware_address").(string))
 	if err != nil || !done {
-		return fmt.Errorf(
+		return diag.Errorf(
 			"error deleting VM card %s on device: %s: %s",
 			d.Get("vm_name").(string),
 			d.Get("hardware_address").(string),
@@ -78,10 +85,10 @@ func landbVMCardResourceDelete(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
-func landbVMCardResourceRead(d *schema.ResourceData, meta interface{}) error {
+func landbVMCardResourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	return nil
 }
 
-func landbVMCardResourceImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+func landbVMCardResourceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	return []*schema.ResourceData{d}, nil
 }
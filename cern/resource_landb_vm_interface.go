@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -13,11 +15,17 @@ func landbVMInterfaceResource() *schema.Resource {
 
 		SchemaVersion: 1,
 
-		Read:   landbVMInterfaceResourceRead,
-		Create: landbVMInterfaceResourceCreate,
-		Delete: landbVMInterfaceResourceDelete,
+		ReadContext:   landbVMInterfaceResourceRead,
+		CreateContext: landbVMInterfaceResourceCreate,
+		DeleteContext: landbVMInterfaceResourceDelete,
 		Importer: &schema.ResourceImporter{
-			State: landbVMInterfaceResourceImport,
+			StateContext: landbVMInterfaceResourceImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -50,10 +58,10 @@ func landbVMInterfaceResource() *schema.Resource {
 	}
 }
 
-func landbVMInterfaceResourceCreate(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMInterfaceResourceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	vmInterfaceOptions := d.Get("vm_interface_options").(map[string]interface{})
@@ -70,9 +78,9 @@ func landbVMInterfaceResourceCreate(d *schema.ResourceData, meta interface{}) er
 		},
 	}
 
-	done, err := landbClient.VMAddInterface(context.TODO(), interfaceRequest)
+	done, err := landbClient.VMAddInterface(ctx, interfaceRequest)
 	if err != nil || !done {
-		return fmt.Errorf(
+		return diag.Errorf(
 			"error creating VM interface %s for device %s: %s",
 			interfaceName,
 			d.Get("vm_name").(string),
@@ -82,16 +90,16 @@ func landbVMInterfaceResourceCreate(d *schema.ResourceData, meta interface{}) er
 	return nil
 }
 
-func landbVMInterfaceResourceDelete(d *schema.ResourceData, meta interface{}) error {
-	landbClient, err := meta.(CernConfig).GetLandbClient()
+func landbVMInterfaceResourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	landbClient, err := meta.(CernConfig).GetLandbClient(ctx)
 	if err != nil {
-		return err
+		return diag.FromErr(err)
 	}
 
 	interfaceName := strings.ToUpper(fmt.Sprintf("%s.%s", d.Get("vm_name").(string), d.Get("interface_domain").(string)))
-	done, err := landbClient.VMRemoveInterface(context.TODO(), d.Get("vm_name").(string), interfaceName)
+	done, err := landbClient.VMRemoveInterface(ctx, d.Get("vm_name").(string), interfaceName)
 	if err != nil || !done {
-		return fmt.Errorf(
+		return diag.Errorf(
 			"error deleting VM interface %s on device: %s: %s",
 			d.Get("vm_name").(string),
 			interfaceName,
@@ -100,10 +108,10 @@ func landbVMInterfaceResourceDelete(d *schema.ResourceData, meta interface{}) er
 	return nil
 }
 
-func landbVMInterfaceResourceRead(d *schema.ResourceData, meta interface{}) error {
+func landbVMInterfaceResourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	return nil
 }
 
-func landbVMInterfaceResourceImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+func landbVMInterfaceResourceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	return []*schema.ResourceData{d}, nil
 }
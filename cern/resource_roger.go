@@ -2,9 +2,13 @@ package cern
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -14,59 +18,101 @@ func rogerResource() *schema.Resource {
 		ReadContext:   resourceRogerRead,
 		UpdateContext: resourceRogerUpdate,
 		DeleteContext: resourceRogerDelete,
+		CustomizeDiff: customdiff.All(
+			resourceRogerHostnameDiff,
+			verifyRogerAppStateDiff,
+			resourceRogerCustomizeDiff,
+		),
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
 
 		Schema: map[string]*schema.Schema{
 			"hostname": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Hostname to update the roger state with",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Hostname to update the roger state with, short or fully qualified",
+				DiffSuppressFunc: suppressHostnameDiff,
 			},
 			"appstate": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Application state of the host, checked against the ones accepted by Roger",
 			},
 			"app_alarmed": {
 				Type:        schema.TypeBool,
-				Description: "Toggle on or off application alarms",
+				Description: "Toggle on or off application alarms, left as they are when not set",
 				Optional:    true,
-				Default:     false,
+				Computed:    true,
 			},
 			"hw_alarmed": {
 				Type:        schema.TypeBool,
-				Description: "Toggle on or off hardware alarms",
+				Description: "Toggle on or off hardware alarms, left as they are when not set",
 				Optional:    true,
-				Default:     false,
+				Computed:    true,
 			},
 			"nc_alarmed": {
 				Type:        schema.TypeBool,
-				Description: "Toggle on or off no contact alarms",
+				Description: "Toggle on or off no contact alarms, left as they are when not set",
 				Optional:    true,
-				Default:     false,
+				Computed:    true,
 			},
 			"os_alarmed": {
 				Type:        schema.TypeBool,
-				Description: "Toggle on or off operating system alarms",
+				Description: "Toggle on or off operating system alarms, left as they are when not set",
 				Optional:    true,
-				Default:     false,
+				Computed:    true,
 			},
 			"message": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
 			},
+			"restore_on_destroy": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Put the roger state back to the one the host had when the " +
+					"resource was created on destroy, instead of deleting it. It can only " +
+					"be enabled when the resource is created",
+			},
+			// The snapshot is kept in the public state since the SDK does not let
+			// resources write their private state. It holds no secret, only the
+			// state Roger shows to anyone allowed to read it.
+			"restore_state": {
+				Type:     schema.TypeString,
+				Computed: true,
+				Description: "Roger state of the host before the resource was created, as JSON, " +
+					"null when it had none",
+			},
 			"expires": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
+				Description: "Expiry of the state, as a RFC3339 date or relative to the time " +
+					"the state is applied like +2d (units: m, h, d, w)",
+				ValidateFunc:     validateRogerExpires,
+				DiffSuppressFunc: suppressEquivalentRogerExpires,
 			},
 			"expires_dt": {
-				Type:     schema.TypeString,
-				Computed: true,
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Expiry of the state, as a RFC3339 date",
+			},
+			"expired": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether or not the state expired on the server",
+			},
+			"recreate_after_expiry": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Treat the state as gone once it expired so that it is created " +
+					"again, instead of applying it again in place. A state which expired at " +
+					"an absolute 'expires' date is left as is, and needs a later date to be changed",
 			},
 			"update_time": {
 				Type:     schema.TypeString,
@@ -88,87 +134,293 @@ func rogerResource() *schema.Resource {
 	}
 }
 
-func resourceRogerCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+// rogerRequest builds the Roger request out of the resource configuration.
+// The alarms which are not set in the configuration are left out, so that
+// Roger keeps their current value, while false is sent explicitly.
+func rogerRequest(d *schema.ResourceData, hostname string) RogerRequest {
+	return RogerRequest{
+		Hostname:   hostname,
+		AppState:   d.Get("appstate").(string),
+		Expires:    NormalizeRogerExpires(d.Get("expires").(string), time.Now()),
+		Message:    d.Get("message").(string),
+		AppAlarmed: configuredBool(d, "app_alarmed"),
+		HwAlarmed:  configuredBool(d, "hw_alarmed"),
+		NcAlarmed:  configuredBool(d, "nc_alarmed"),
+		OsAlarmed:  configuredBool(d, "os_alarmed"),
+	}
+}
+
+// configuredBool returns the value of a boolean attribute, or nil when it is
+// not set in the configuration. GetOk cannot be used as it does not tell
+// false apart from unset.
+func configuredBool(d *schema.ResourceData, key string) *bool {
+	value := d.GetRawConfig().GetAttr(key)
+	if value.IsNull() || !value.IsKnown() {
+		return nil
+	}
+	b := value.True()
+	return &b
+}
+
+// suppressHostnameDiff leaves the hostnames of an existing state to
+// resourceRogerHostnameDiff, which knows the domain of the short names
+func suppressHostnameDiff(k, old, new string, d *schema.ResourceData) bool {
+	return old != ""
+}
+
+// resourceRogerHostnameDiff replaces the resource when the hostname points to
+// another host, the short and fully qualified names of a host being the same.
+// The diff suppression is not applied again after ForceNew.
+func resourceRogerHostnameDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
 	client := meta.(*config).RogerClient
+	if d.Id() == "" {
+		return nil
+	}
 
-	request := RogerRequest{
-		Hostname: d.Get("hostname").(string),
-		AppState: d.Get("appstate").(string),
-		Expires:  d.Get("expires").(string),
-		Message:  d.Get("message").(string),
+	o, n := d.GetChange("hostname")
+	if d.NewValueKnown("hostname") && client.CanonicalHostname(o.(string)) == client.CanonicalHostname(n.(string)) {
+		return nil
 	}
-	appAlarmed := d.Get("app_alarmed").(bool)
-	if appAlarmed {
-		request.AppAlarmed = &appAlarmed
+	return d.ForceNew("hostname")
+}
+
+func validateRogerExpires(v interface{}, k string) ([]string, []error) {
+	if v.(string) == "" {
+		return nil, nil
 	}
-	hwAlarmed := d.Get("hw_alarmed").(bool)
-	if appAlarmed {
-		request.HwAlarmed = &hwAlarmed
+	if _, err := ParseRogerExpires(v.(string), time.Now()); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
 	}
-	ncAlarmed := d.Get("nc_alarmed").(bool)
-	if ncAlarmed {
-		request.NcAlarmed = &ncAlarmed
+	return nil, nil
+}
+
+// suppressEquivalentRogerExpires hides the differences between absolute
+// expiries written in different formats
+func suppressEquivalentRogerExpires(k, old, new string, d *schema.ResourceData) bool {
+	if IsRelativeRogerExpires(old) || IsRelativeRogerExpires(new) {
+		return old == new
 	}
-	osAlarmed := d.Get("os_alarmed").(bool)
-	if ncAlarmed {
-		request.OsAlarmed = &osAlarmed
+	now := time.Now()
+	oldTime, err := ParseRogerExpires(old, now)
+	if err != nil {
+		return false
 	}
+	newTime, err := ParseRogerExpires(new, now)
+	if err != nil {
+		return false
+	}
+	return oldTime.Equal(newTime)
+}
 
-	log.Printf("[DEBUG] Creating roger create request for %s", request.Hostname)
-	err := client.Create(ctx, request)
+// formatRogerExpiresDt converts the expiry date returned by Roger to RFC3339,
+// returning it unchanged when it cannot be parsed
+func formatRogerExpiresDt(expiresDt string) string {
+	if expiresDt == "" {
+		return ""
+	}
+	t, err := ParseRogerExpires(expiresDt, time.Now())
 	if err != nil {
-		return diag.Errorf("Error creating roger state: %s", err)
+		return expiresDt
+	}
+	return t.Format(time.RFC3339)
+}
+
+// rogerExpired returns true when the expiry date returned by Roger is in the
+// past
+func rogerExpired(resp *RogerResponse) bool {
+	if resp.ExpiresDt == "" {
+		return false
+	}
+	t, err := ParseRogerExpires(resp.ExpiresDt, time.Now())
+	return err == nil && t.Before(time.Now())
+}
+
+// verifyRogerAppStateDiff checks at plan time that the configured appstate is
+// accepted by Roger
+func verifyRogerAppStateDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	client := meta.(*config).RogerClient
+	if !d.NewValueKnown("appstate") || !d.HasChange("appstate") {
+		return nil
+	}
+
+	appState := d.Get("appstate").(string)
+	if appState == "" {
+		return nil
+	}
+	return client.ValidateAppState(appState)
+}
+
+// resourceRogerCustomizeDiff plans to apply the state again once it expired
+// on the server, and rejects the absolute expiries already in the past as
+// well as a restore_on_destroy enabled too late. A state which expired at the
+// configured date is left as is, and cannot be changed without a new date.
+func resourceRogerCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	expires := d.Get("expires").(string)
+	absolute := expires != "" && !IsRelativeRogerExpires(expires)
+
+	// HasChange does not take the diff suppression into account, so the same
+	// date written differently would count as a change
+	o, n := d.GetChange("expires")
+	changed := !suppressEquivalentRogerExpires("expires", o.(string), n.(string), nil)
+
+	// Only a date set by the user is checked, the configured one is expected
+	// to pass at some point
+	if changed && absolute {
+		t, err := ParseRogerExpires(expires, time.Now())
+		if err == nil && t.Before(time.Now()) {
+			return fmt.Errorf("expires %s is in the past, use a later date or a relative duration like +2d", expires)
+		}
+	}
+
+	// The state the host had before is only known when the resource is
+	// created, enabling the restore later would delete the state on destroy
+	if d.Id() != "" && d.Get("restore_on_destroy").(bool) && d.Get("restore_state").(string) == "" {
+		return fmt.Errorf("restore_on_destroy can only be enabled when the resource is created, " +
+			"the roger state the host had before is unknown")
+	}
+
+	// Changing a state which expired at the configured date would send that
+	// date again
+	if d.Id() != "" && d.Get("expired").(bool) && absolute && !changed {
+		for _, key := range rogerExpiringAttributes {
+			if d.HasChange(key) {
+				return fmt.Errorf("roger state of %s expired on %s, set a later expires date or a relative "+
+					"duration like +2d to change %s", d.Id(), expires, key)
+			}
+		}
+	}
+
+	if d.Id() != "" && d.Get("expired").(bool) && (!absolute || changed) {
+		return d.SetNew("expired", false)
 	}
+	return nil
+}
+
+// rogerExpiringAttributes are the attributes of a Roger state which are
+// applied until it expires
+var rogerExpiringAttributes = []string{
+	"appstate", "app_alarmed", "hw_alarmed", "nc_alarmed", "os_alarmed", "message",
+}
+
+// rogerStateAttributes flattens a Roger state into the attributes shared by
+// the Roger resource and data sources
+func rogerStateAttributes(resp *RogerResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"appstate":          resp.AppState,
+		"app_alarmed":       boolValue(resp.AppAlarmed),
+		"hw_alarmed":        boolValue(resp.HwAlarmed),
+		"nc_alarmed":        boolValue(resp.NcAlarmed),
+		"os_alarmed":        boolValue(resp.OsAlarmed),
+		"message":           resp.Message,
+		"expires":           resp.Expires,
+		"expires_dt":        formatRogerExpiresDt(resp.ExpiresDt),
+		"update_time":       resp.UpdateTime,
+		"update_time_dt":    resp.UpdateTimeDt,
+		"updated_by":        resp.UpdatedBy,
+		"updated_by_puppet": resp.UpdatedByPuppet,
+	}
+}
+
+func resourceRogerCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+
+	request := rogerRequest(d, d.Get("hostname").(string))
 
-	d.SetId(request.Hostname)
+	// The state is snapshotted before being changed so that it can be put
+	// back on destroy. A host without state is simply deleted again.
+	var previous *RogerResponse
+	if d.Get("restore_on_destroy").(bool) {
+		log.Printf("[DEBUG] Creating roger read request for %s", request.Hostname)
+		resp, err := client.Get(ctx, request.Hostname)
+		if err != nil && !IsNotFound(err) {
+			return diag.Errorf("Error reading roger state: %s", err)
+		}
+		previous = resp
+	}
+
+	if previous != nil {
+		log.Printf("[DEBUG] Creating roger update request for %s", request.Hostname)
+		if err := client.Update(ctx, request); err != nil {
+			return diag.Errorf("Error updating roger state: %s", err)
+		}
+	} else {
+		log.Printf("[DEBUG] Creating roger create request for %s", request.Hostname)
+		err := client.Create(ctx, request)
+
+		// The state is still there when it is created again after expiring,
+		// or when it was set outside of Terraform
+		if IsConflict(err) {
+			log.Printf("[DEBUG] Creating roger update request for existing state of %s", request.Hostname)
+			err = client.Update(ctx, request)
+		}
+		if err != nil {
+			return diag.Errorf("Error creating roger state: %s", err)
+		}
+	}
+
+	d.SetId(client.CanonicalHostname(request.Hostname))
+	if d.Get("restore_on_destroy").(bool) {
+		var snapshot *RogerRequest
+		if previous != nil {
+			snapshot = &previous.RogerRequest
+		}
+		encoded, err := json.Marshal(snapshot)
+		if err != nil {
+			return diag.Errorf("Unable to encode roger state: %s", err)
+		}
+		if err := d.Set("restore_state", string(encoded)); err != nil {
+			return diag.Errorf("Unable to set restore_state: %s", err)
+		}
+	}
 
 	return resourceRogerRead(ctx, d, meta)
 }
 
 func resourceRogerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*config).RogerClient
-	hostname := d.Id()
+	hostname := client.CanonicalHostname(d.Id())
+	d.SetId(hostname)
 	log.Printf("[DEBUG] Creating roger read request for %s", hostname)
 	resp, err := client.Get(ctx, hostname)
 	if err != nil {
 		return diag.FromErr(CheckDeleted(d, "Error reading roger state", err))
 	}
 
+	// Creating the state again only makes sense when it gets a new expiry
+	expired := rogerExpired(resp)
+	expires := d.Get("expires").(string)
+	if expired && d.Get("recreate_after_expiry").(bool) && (expires == "" || IsRelativeRogerExpires(expires)) {
+		log.Printf("[WARN] Roger state of %s expired, removing it from state", hostname)
+		d.SetId("")
+		return nil
+	}
+
+	// The relative expiry is kept as configured, the date it resolved to is
+	// available in expires_dt
 	if err := d.Set("hostname", hostname); err != nil {
 		return diag.Errorf("Unable to set hostname: %s", err)
 	}
-	if err := d.Set("appstate", resp.AppState); err != nil {
-		return diag.Errorf("Error setting appstate: %s", err)
-	}
-	if err := d.Set("app_alarmed", resp.AppAlarmed); err != nil {
-		return diag.Errorf("Error setting app_alarmed: %s", err)
-	}
-	if err := d.Set("hw_alarmed", resp.HwAlarmed); err != nil {
-		return diag.Errorf("Error setting hw_alarmed: %s", err)
-	}
-	if err := d.Set("os_alarmed", resp.OsAlarmed); err != nil {
-		return diag.Errorf("Error setting os_alarmed: %s", err)
-	}
-	if err := d.Set("message", resp.Message); err != nil {
-		return diag.Errorf("Error setting message: %s", err)
-	}
-	if err := d.Set("expires", resp.Expires); err != nil {
-		return diag.Errorf("Error setting expires: %s", err)
+	attributes := rogerStateAttributes(resp)
+	if expired && expires != "" && !IsRelativeRogerExpires(expires) && d.Get("appstate").(string) != "" {
+		// Roger may put the host back to its previous state once the absolute
+		// expiry passed, the applied values are kept so that the plan does not
+		// send them again with a date in the past
+		for _, key := range append(rogerExpiringAttributes, "expires") {
+			delete(attributes, key)
+		}
 	}
-	if err := d.Set("expires_dt", resp.ExpiresDt); err != nil {
-		return diag.Errorf("Error setting expires_dt: %s", err)
+	for key, value := range attributes {
+		if err := d.Set(key, value); err != nil {
+			return diag.Errorf("Error setting %s: %s", key, err)
+		}
 	}
-	if err := d.Set("update_time", resp.UpdateTime); err != nil {
-		return diag.Errorf("Error setting update_time: %s", err)
+	if IsRelativeRogerExpires(expires) {
+		if err := d.Set("expires", expires); err != nil {
+			return diag.Errorf("Error setting expires: %s", err)
+		}
 	}
-	if err := d.Set("update_time_dt", resp.UpdateTimeDt); err != nil {
-		return diag.Errorf("Error setting update_time_dt: %s", err)
-	}
-	if err := d.Set("updated_by", resp.UpdatedBy); err != nil {
-		return diag.Errorf("Error setting updated_by: %s", err)
-	}
-	if err := d.Set("updated_by_puppet", resp.UpdatedByPuppet); err != nil {
-		return diag.Errorf("Error setting updated_by_puppet: %s", err)
+	if err := d.Set("expired", expired); err != nil {
+		return diag.Errorf("Error setting expired: %s", err)
 	}
 
 	return nil
@@ -177,28 +429,7 @@ func resourceRogerRead(ctx context.Context, d *schema.ResourceData, meta interfa
 func resourceRogerUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*config).RogerClient
 
-	request := RogerRequest{
-		Hostname: d.Get("hostname").(string),
-		AppState: d.Get("appstate").(string),
-		Expires:  d.Get("expires").(string),
-		Message:  d.Get("message").(string),
-	}
-	appAlarmed := d.Get("app_alarmed").(bool)
-	if appAlarmed {
-		request.AppAlarmed = &appAlarmed
-	}
-	hwAlarmed := d.Get("hw_alarmed").(bool)
-	if appAlarmed {
-		request.HwAlarmed = &hwAlarmed
-	}
-	ncAlarmed := d.Get("nc_alarmed").(bool)
-	if ncAlarmed {
-		request.NcAlarmed = &ncAlarmed
-	}
-	osAlarmed := d.Get("os_alarmed").(bool)
-	if ncAlarmed {
-		request.OsAlarmed = &osAlarmed
-	}
+	request := rogerRequest(d, d.Get("hostname").(string))
 
 	log.Printf("[DEBUG] Creating roger update request for %s", request.Hostname)
 	err := client.Update(ctx, request)
@@ -213,6 +444,29 @@ func resourceRogerDelete(ctx context.Context, d *schema.ResourceData, meta inter
 	client := meta.(*config).RogerClient
 	hostname := d.Get("hostname").(string)
 
+	// A host which had no state before is simply deleted again
+	if d.Get("restore_on_destroy").(bool) {
+		snapshot := d.Get("restore_state").(string)
+		if snapshot == "" {
+			return diag.Errorf("No roger state of %s to restore, set restore_on_destroy to false to delete it", hostname)
+		}
+
+		var request *RogerRequest
+		if err := json.Unmarshal([]byte(snapshot), &request); err != nil {
+			return diag.Errorf("Unable to decode restore_state: %s", err)
+		}
+		if request != nil {
+			request.Hostname = hostname
+
+			log.Printf("[DEBUG] Creating roger update request for %s to restore its state", hostname)
+			err := client.Update(ctx, *request)
+			if err != nil {
+				return diag.FromErr(CheckDeleted(d, "Error restoring roger state", err))
+			}
+			return nil
+		}
+	}
+
 	log.Printf("[DEBUG] Creating roger delete request for %s", hostname)
 	_, err := client.Delete(ctx, hostname)
 	if err != nil {
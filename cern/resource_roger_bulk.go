@@ -0,0 +1,337 @@
+package cern
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// rogerBulkStateKeys are the attributes describing the state applied to
+// every host
+var rogerBulkStateKeys = []string{
+	"appstate", "message", "expires", "app_alarmed", "hw_alarmed", "nc_alarmed", "os_alarmed",
+}
+
+func rogerBulkResource() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceRogerBulkCreate,
+		ReadContext:   resourceRogerBulkRead,
+		UpdateContext: resourceRogerBulkUpdate,
+		DeleteContext: resourceRogerBulkDelete,
+		CustomizeDiff: verifyRogerAppStateDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"hostnames": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				Description: "Hostnames to update the roger state of, short or fully qualified",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"appstate": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Application state of the hosts",
+			},
+			"app_alarmed": {
+				Type:        schema.TypeBool,
+				Description: "Toggle on or off application alarms, left as they are when not set",
+				Optional:    true,
+			},
+			"hw_alarmed": {
+				Type:        schema.TypeBool,
+				Description: "Toggle on or off hardware alarms, left as they are when not set",
+				Optional:    true,
+			},
+			"nc_alarmed": {
+				Type:        schema.TypeBool,
+				Description: "Toggle on or off no contact alarms, left as they are when not set",
+				Optional:    true,
+			},
+			"os_alarmed": {
+				Type:        schema.TypeBool,
+				Description: "Toggle on or off operating system alarms, left as they are when not set",
+				Optional:    true,
+			},
+			"message": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Message attached to the state of the hosts",
+			},
+			"expires": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Expiry of the state of the hosts, as a RFC3339 date or relative to the time " +
+					"the state is applied like +2d (units: m, h, d, w)",
+				ValidateFunc: validateRogerExpires,
+			},
+			"parallelism": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      10,
+				Description:  "Maximum number of hosts updated at the same time",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+		},
+	}
+}
+
+var errRogerStateDrifted = errors.New("roger state changed outside of Terraform")
+
+// forEachRogerHost runs fn for every host with at most 'parallelism' of them
+// at the same time, and returns the error of every host, nil on success
+func forEachRogerHost(ctx context.Context, hosts []string, parallelism int, fn func(ctx context.Context, host string) error) map[string]error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make(map[string]error, len(hosts))
+
+	sem := make(chan struct{}, parallelism)
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := fn(ctx, host)
+
+			mu.Lock()
+			errs[host] = err
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// rogerHostDiagnostics returns an error diagnostic for every failed host, so
+// that one host does not hide the others
+func rogerHostDiagnostics(summary string, errs map[string]error) diag.Diagnostics {
+	var hosts []string
+	for host, err := range errs {
+		if err != nil {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	var diags diag.Diagnostics
+	for _, host := range hosts {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("%s of %s", summary, host),
+			Detail:   errs[host].Error(),
+		})
+	}
+	return diags
+}
+
+// applyRogerBulkState sets the state of the given hosts, creating it for the
+// hosts which do not have one yet
+func applyRogerBulkState(ctx context.Context, d *schema.ResourceData, client *Roger, hosts []string) map[string]error {
+	return forEachRogerHost(ctx, hosts, d.Get("parallelism").(int), func(ctx context.Context, host string) error {
+		request := rogerRequest(d, host)
+
+		log.Printf("[DEBUG] Creating roger update request for %s", host)
+		err := client.Update(ctx, request)
+		if IsNotFound(err) {
+			log.Printf("[DEBUG] Creating roger create request for %s", host)
+			err = client.Create(ctx, request)
+		}
+		return err
+	})
+}
+
+// deleteRogerBulkState deletes the state of the given hosts, ignoring the
+// ones that are already gone
+func deleteRogerBulkState(ctx context.Context, d *schema.ResourceData, client *Roger, hosts []string) map[string]error {
+	return forEachRogerHost(ctx, hosts, d.Get("parallelism").(int), func(ctx context.Context, host string) error {
+		log.Printf("[DEBUG] Creating roger delete request for %s", host)
+		_, err := client.Delete(ctx, host)
+		if IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func resourceRogerBulkCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+	hosts := newRogerBulkHosts(client, d.Get("hostnames").(*schema.Set))
+
+	errs := applyRogerBulkState(ctx, d, client, hosts.canonical())
+	diags := rogerHostDiagnostics("Error updating roger state", errs)
+
+	// Only the hosts which were updated are recorded, the other ones are
+	// retried on the next apply
+	applied := schema.NewSet(schema.HashString, nil)
+	for host, err := range errs {
+		if err == nil {
+			for _, name := range hosts[host] {
+				applied.Add(name)
+			}
+		}
+	}
+	if applied.Len() == 0 && diags.HasError() {
+		return diags
+	}
+
+	d.SetId(id.UniqueId())
+	if err := d.Set("hostnames", applied); err != nil {
+		return append(diags, diag.Errorf("Unable to set hostnames: %s", err)...)
+	}
+
+	// Errors would taint the resource and replace it, updating every host
+	// again, so the failed hosts are only reported as warnings
+	for i := range diags {
+		diags[i].Severity = diag.Warning
+		diags[i].Detail += ", the state is applied again on the next apply"
+	}
+
+	return append(diags, resourceRogerBulkRead(ctx, d, meta)...)
+}
+
+func resourceRogerBulkRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+	hosts := newRogerBulkHosts(client, d.Get("hostnames").(*schema.Set))
+	appstate := d.Get("appstate").(string)
+
+	errs := forEachRogerHost(ctx, hosts.canonical(), d.Get("parallelism").(int), func(ctx context.Context, host string) error {
+		log.Printf("[DEBUG] Creating roger read request for %s", host)
+		resp, err := client.Get(ctx, host)
+		if err != nil {
+			return err
+		}
+		if appstate != "" && resp.AppState != appstate {
+			return errRogerStateDrifted
+		}
+		return nil
+	})
+
+	// The hosts whose state is gone or was changed outside of Terraform are
+	// removed, so that the plan applies the state to them again
+	current := schema.NewSet(schema.HashString, nil)
+	for host, err := range errs {
+		if errors.Is(err, errRogerStateDrifted) || IsNotFound(err) {
+			log.Printf("[WARN] Roger state of %s is missing or was modified outside of Terraform", host)
+			errs[host] = nil
+			continue
+		}
+		for _, name := range hosts[host] {
+			current.Add(name)
+		}
+	}
+	if diags := rogerHostDiagnostics("Error reading roger state", errs); diags.HasError() {
+		return diags
+	}
+
+	if err := d.Set("hostnames", current); err != nil {
+		return diag.Errorf("Unable to set hostnames: %s", err)
+	}
+
+	return nil
+}
+
+func resourceRogerBulkUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+
+	o, n := d.GetChange("hostnames")
+	oldHosts := newRogerBulkHosts(client, o.(*schema.Set))
+	newHosts := newRogerBulkHosts(client, n.(*schema.Set))
+
+	// Every host is updated when the state changes, otherwise only the new
+	// ones. A host only listed under another name is left as is.
+	var toApply, toDelete []string
+	for _, host := range newHosts.canonical() {
+		if _, ok := oldHosts[host]; !ok || d.HasChanges(rogerBulkStateKeys...) {
+			toApply = append(toApply, host)
+		}
+	}
+	for _, host := range oldHosts.canonical() {
+		if _, ok := newHosts[host]; !ok {
+			toDelete = append(toDelete, host)
+		}
+	}
+
+	applyErrs := applyRogerBulkState(ctx, d, client, toApply)
+	deleteErrs := deleteRogerBulkState(ctx, d, client, toDelete)
+
+	// The hosts which failed to get the new state are dropped, and the ones
+	// which could not be deleted are kept, so that they are retried
+	hosts := n.(*schema.Set)
+	for host, err := range applyErrs {
+		if err != nil {
+			for _, name := range newHosts[host] {
+				hosts.Remove(name)
+			}
+		}
+	}
+	for host, err := range deleteErrs {
+		if err != nil {
+			for _, name := range oldHosts[host] {
+				hosts.Add(name)
+			}
+		}
+	}
+
+	diags := rogerHostDiagnostics("Error updating roger state", applyErrs)
+	diags = append(diags, rogerHostDiagnostics("Error deleting roger state", deleteErrs)...)
+	if err := d.Set("hostnames", hosts); err != nil {
+		return append(diags, diag.Errorf("Unable to set hostnames: %s", err)...)
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	return resourceRogerBulkRead(ctx, d, meta)
+}
+
+func resourceRogerBulkDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).RogerClient
+	hosts := newRogerBulkHosts(client, d.Get("hostnames").(*schema.Set))
+
+	errs := deleteRogerBulkState(ctx, d, client, hosts.canonical())
+	return rogerHostDiagnostics("Error deleting roger state", errs)
+}
+
+// rogerBulkHosts groups the configured hostnames by the name Roger knows the
+// hosts under, so that a host listed with both its short and fully qualified
+// names is only updated once
+type rogerBulkHosts map[string][]string
+
+func newRogerBulkHosts(client *Roger, set *schema.Set) rogerBulkHosts {
+	hosts := make(rogerBulkHosts)
+	for _, value := range set.List() {
+		host := client.CanonicalHostname(value.(string))
+		hosts[host] = append(hosts[host], value.(string))
+	}
+	return hosts
+}
+
+// canonical returns the sorted names Roger knows the hosts under
+func (h rogerBulkHosts) canonical() []string {
+	hosts := make([]string, 0, len(h))
+	for host := range h {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
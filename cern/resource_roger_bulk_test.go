@@ -0,0 +1,61 @@
+package cern
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+func TestRogerBulkCreatePartialFailure(t *testing.T) {
+	client, _ := newTestRoger(t)
+	meta := &config{RogerClient: client}
+
+	state, diags := applyTestConfig(t, rogerBulkResource(), meta, nil, map[string]interface{}{
+		"hostnames": []interface{}{"myhost", "broken"},
+		"appstate":  "draining",
+	})
+
+	if state == nil || state.ID == "" {
+		t.Fatal("expected the resource to be created")
+	}
+	if state.Tainted {
+		t.Error("expected the resource not to be tainted")
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Summary, "broken") {
+		t.Errorf("expected a warning about the broken host, got %v", diags)
+	}
+	if state.Attributes["hostnames.#"] != "1" {
+		t.Errorf("expected only the working host to be recorded, got %v", state.Attributes)
+	}
+}
+
+func TestRogerBulkEquivalentHostnames(t *testing.T) {
+	client, requests := newTestRoger(t)
+	meta := &config{RogerClient: client}
+
+	state, _ := applyTestConfig(t, rogerBulkResource(), meta, nil, map[string]interface{}{
+		"hostnames": []interface{}{"myhost", "MyHost.cern.ch"},
+		"appstate":  "draining",
+	})
+	state, _ = applyTestConfig(t, rogerBulkResource(), meta, state, map[string]interface{}{
+		"hostnames": []interface{}{"myhost.cern.ch", "otherhost"},
+		"appstate":  "draining",
+	})
+
+	var updated []string
+	for _, request := range requests() {
+		updated = append(updated, request.Method+" "+request.Body["hostname"].(string))
+	}
+	expected := []string{"PUT myhost.cern.ch", "POST myhost.cern.ch", "PUT otherhost.cern.ch", "POST otherhost.cern.ch"}
+	if strings.Join(updated, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected each host to be updated once, got %v", updated)
+	}
+	if state.Attributes["hostnames.#"] != "2" {
+		t.Errorf("expected the configured hostnames to be recorded, got %v", state.Attributes)
+	}
+	if _, err := client.Get(context.Background(), "myhost"); err != nil {
+		t.Errorf("expected the state of myhost to be kept, got %s", err)
+	}
+}
@@ -0,0 +1,411 @@
+package cern
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// rogerTestRequest is a request received by the Roger stand-in
+type rogerTestRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// newTestRoger returns a Roger client talking to an in-memory stand-in of the
+// Roger API, and the create and update requests it receives. The requests
+// about the hosts named broken* fail.
+func newTestRoger(t *testing.T) (*Roger, func() []rogerTestRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []rogerTestRequest
+	states := make(map[string][]byte)
+
+	serverURL, httpClient, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		hostname := strings.Trim(strings.TrimPrefix(r.URL.Path, "/roger/v1/state/"), "/")
+		var body []byte
+		if r.Method == "POST" || r.Method == "PUT" {
+			var request RogerRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body, _ = json.Marshal(request)
+
+			var fields map[string]interface{}
+			json.Unmarshal(body, &fields)
+			requests = append(requests, rogerTestRequest{Method: r.Method, Path: r.URL.Path, Body: fields})
+			if r.Method == "POST" {
+				hostname = request.Hostname
+			}
+
+			// Roger resolves the absolute expiries into expires_dt
+			if request.Expires != "" && !IsRelativeRogerExpires(request.Expires) {
+				body, _ = json.Marshal(RogerResponse{RogerRequest: request, ExpiresDt: request.Expires})
+			}
+		}
+
+		// The hosts named broken* always fail
+		if strings.HasPrefix(hostname, "broken") {
+			http.Error(w, `{"message": "broken host"}`, http.StatusInternalServerError)
+			return
+		}
+
+		_, exists := states[hostname]
+		switch {
+		case r.Method == "POST" && exists:
+			w.WriteHeader(http.StatusConflict)
+		case r.Method == "POST":
+			states[hostname] = body
+			w.WriteHeader(http.StatusCreated)
+		case !exists:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "PUT":
+			states[hostname] = body
+		case r.Method == "DELETE":
+			delete(states, hostname)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write(states[hostname])
+		}
+	})
+
+	hosts, err := NewHostNormalizer("cern.ch", HostVerificationNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Roger{
+		URL:        serverURL,
+		HTTPClient: httpClient,
+		AppStates:  DefaultRogerAppStates,
+		Hosts:      hosts,
+	}
+	return client, func() []rogerTestRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]rogerTestRequest(nil), requests...)
+	}
+}
+
+// applyTestConfig plans and applies the given configuration of a resource the
+// way Terraform does, including the raw configuration used to tell unset
+// attributes apart from false ones
+func applyTestConfig(t *testing.T, r *schema.Resource, meta *config, state *terraform.InstanceState, raw map[string]interface{}) (*terraform.InstanceState, diag.Diagnostics) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	diff, err := r.Diff(ctx, state, terraform.NewResourceConfigRaw(raw), meta)
+	if err != nil {
+		t.Fatalf("unexpected diff error: %s", err)
+	}
+
+	rawJSON, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff.RawConfig, err = ctyjson.Unmarshal(rawJSON, r.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newState, diags := r.Apply(ctx, state, diff, meta)
+	if diags.HasError() {
+		t.Fatalf("unexpected apply error: %v", diags)
+	}
+	return newState, diags
+}
+
+// applyRogerConfig applies the given configuration of a cern_roger resource
+func applyRogerConfig(t *testing.T, meta *config, state *terraform.InstanceState, raw map[string]interface{}) *terraform.InstanceState {
+	t.Helper()
+
+	newState, _ := applyTestConfig(t, rogerResource(), meta, state, raw)
+	return newState
+}
+
+func TestRogerRequestAlarms(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		sent   map[string]interface{}
+	}{
+		{
+			name: "false alarm is sent",
+			config: map[string]interface{}{
+				"hostname":    "myhost",
+				"appstate":    "production",
+				"app_alarmed": false,
+			},
+			sent: map[string]interface{}{
+				"app_alarmed": false,
+			},
+		},
+		{
+			name: "unset alarms are left out",
+			config: map[string]interface{}{
+				"hostname": "myhost",
+				"appstate": "intervention",
+			},
+			sent: map[string]interface{}{},
+		},
+		{
+			name: "alarms are independent",
+			config: map[string]interface{}{
+				"hostname":    "myhost",
+				"appstate":    "production",
+				"app_alarmed": true,
+				"nc_alarmed":  false,
+			},
+			sent: map[string]interface{}{
+				"app_alarmed": true,
+				"nc_alarmed":  false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newTestRoger(t)
+			applyRogerConfig(t, &config{RogerClient: client}, nil, tt.config)
+
+			received := requests()
+			if len(received) != 1 || received[0].Method != "POST" {
+				t.Fatalf("expected a single POST request, got %+v", received)
+			}
+			checkRogerAlarms(t, received[0].Body, tt.sent)
+			if hostname := received[0].Body["hostname"]; hostname != "myhost.cern.ch" {
+				t.Errorf("expected hostname myhost.cern.ch, got %v", hostname)
+			}
+		})
+	}
+}
+
+func TestRogerUpdateAlarms(t *testing.T) {
+	client, requests := newTestRoger(t)
+	meta := &config{RogerClient: client}
+
+	state := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname":    "myhost",
+		"appstate":    "production",
+		"app_alarmed": true,
+		"hw_alarmed":  true,
+	})
+	applyRogerConfig(t, meta, state, map[string]interface{}{
+		"hostname":    "myhost",
+		"appstate":    "production",
+		"app_alarmed": false,
+	})
+
+	received := requests()
+	if len(received) != 2 || received[1].Method != "PUT" {
+		t.Fatalf("expected a POST and a PUT request, got %+v", received)
+	}
+	if received[1].Path != "/roger/v1/state/myhost.cern.ch/" {
+		t.Errorf("unexpected update path %s", received[1].Path)
+	}
+	checkRogerAlarms(t, received[1].Body, map[string]interface{}{
+		"app_alarmed": false,
+	})
+}
+
+// checkRogerAlarms checks that exactly the expected alarms were sent
+func checkRogerAlarms(t *testing.T, body map[string]interface{}, expected map[string]interface{}) {
+	t.Helper()
+
+	for _, alarm := range []string{"app_alarmed", "hw_alarmed", "nc_alarmed", "os_alarmed"} {
+		value, sent := body[alarm]
+		want, expectedSent := expected[alarm]
+		switch {
+		case sent && !expectedSent:
+			t.Errorf("expected %s to be left out, got %v", alarm, value)
+		case !sent && expectedSent:
+			t.Errorf("expected %s to be sent as %v", alarm, want)
+		case sent && value != want:
+			t.Errorf("expected %s to be sent as %v, got %v", alarm, want, value)
+		}
+	}
+}
+
+func TestRogerRestoreOnDestroy(t *testing.T) {
+	client, requests := newTestRoger(t)
+	meta := &config{RogerClient: client}
+	ctx := context.Background()
+
+	previous := RogerRequest{Hostname: "myhost", AppState: "production", Message: "before"}
+	if err := client.Create(ctx, previous); err != nil {
+		t.Fatal(err)
+	}
+
+	state := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname":           "myhost",
+		"appstate":           "draining",
+		"restore_on_destroy": true,
+	})
+	if _, diags := rogerResource().Apply(ctx, state, &terraform.InstanceDiff{Destroy: true}, meta); diags.HasError() {
+		t.Fatalf("unexpected destroy error: %v", diags)
+	}
+
+	received := requests()
+	restore := received[len(received)-1]
+	if restore.Method != "PUT" || restore.Body["appstate"] != "production" || restore.Body["message"] != "before" {
+		t.Errorf("expected the previous state to be restored, got %+v", restore)
+	}
+}
+
+func TestRogerRestoreOnDestroyEnabledLater(t *testing.T) {
+	client, _ := newTestRoger(t)
+	meta := &config{RogerClient: client}
+
+	state := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "draining",
+	})
+	_, err := rogerResource().Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"hostname":           "myhost",
+		"appstate":           "draining",
+		"restore_on_destroy": true,
+	}), meta)
+	if err == nil || !strings.Contains(err.Error(), "restore_on_destroy can only be enabled") {
+		t.Errorf("expected restore_on_destroy to be rejected, got %v", err)
+	}
+}
+
+func TestRogerCreateExistingState(t *testing.T) {
+	client, requests := newTestRoger(t)
+	meta := &config{RogerClient: client}
+
+	if err := client.Create(context.Background(), RogerRequest{Hostname: "myhost", AppState: "production"}); err != nil {
+		t.Fatal(err)
+	}
+
+	state := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "draining",
+	})
+	if state.ID != "myhost.cern.ch" || state.Attributes["appstate"] != "draining" {
+		t.Errorf("expected the existing state to be updated, got %+v", state.Attributes)
+	}
+
+	received := requests()
+	update := received[len(received)-1]
+	if update.Method != "PUT" || update.Path != "/roger/v1/state/myhost.cern.ch/" {
+		t.Errorf("expected the create to fall back to an update, got %+v", received)
+	}
+}
+
+func TestRogerExpiredAtConfiguredDate(t *testing.T) {
+	client, _ := newTestRoger(t)
+	meta := &config{RogerClient: client}
+	ctx := context.Background()
+
+	future := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	state := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "draining",
+		"expires":  future,
+	})
+
+	// Roger puts the host back in production once the state expired
+	if err := client.Update(ctx, RogerRequest{Hostname: "myhost", AppState: "production", Expires: past}); err != nil {
+		t.Fatal(err)
+	}
+	state.Attributes["expires"] = past
+	state, diags := rogerResource().RefreshWithoutUpgrade(ctx, state, meta)
+	if diags.HasError() {
+		t.Fatalf("unexpected refresh error: %v", diags)
+	}
+	if state.Attributes["expired"] != "true" || state.Attributes["appstate"] != "draining" {
+		t.Fatalf("expected the applied state to be kept once expired, got %+v", state.Attributes)
+	}
+
+	diff, err := rogerResource().Diff(ctx, state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "draining",
+		"expires":  past,
+	}), meta)
+	if err != nil {
+		t.Fatalf("unexpected diff error: %s", err)
+	}
+	if !diff.Empty() {
+		t.Errorf("expected no changes once expired at the configured date, got %+v", diff.Attributes)
+	}
+
+	_, err = rogerResource().Diff(ctx, state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "intervention",
+		"expires":  past,
+	}), meta)
+	if err == nil || !strings.Contains(err.Error(), "set a later expires date") {
+		t.Errorf("expected the change to be rejected, got %v", err)
+	}
+}
+
+func TestRogerHostnameDiff(t *testing.T) {
+	client, _ := newTestRoger(t)
+	hosts, err := NewHostNormalizer("example.org", HostVerificationNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Hosts = hosts
+	meta := &config{RogerClient: client}
+
+	short := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname": "myhost",
+		"appstate": "draining",
+	})
+	if short.ID != "myhost.example.org" {
+		t.Fatalf("expected the host to be qualified with the configured domain, got %s", short.ID)
+	}
+	qualified := applyRogerConfig(t, meta, nil, map[string]interface{}{
+		"hostname": "otherhost.cern.ch",
+		"appstate": "draining",
+	})
+
+	tests := []struct {
+		name     string
+		state    *terraform.InstanceState
+		hostname string
+		replaced bool
+	}{
+		{name: "same name", state: short, hostname: "myhost"},
+		{name: "fully qualified name", state: short, hostname: "MyHost.example.org."},
+		{name: "other domain", state: short, hostname: "myhost.cern.ch", replaced: true},
+		{name: "other host", state: short, hostname: "otherhost", replaced: true},
+		{name: "short name in the configured domain", state: qualified, hostname: "otherhost", replaced: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := rogerResource().Diff(context.Background(), tt.state, terraform.NewResourceConfigRaw(map[string]interface{}{
+				"hostname": tt.hostname,
+				"appstate": "draining",
+			}), meta)
+			if err != nil {
+				t.Fatalf("unexpected diff error: %s", err)
+			}
+			if replaced := diff != nil && diff.RequiresNew(); replaced != tt.replaced {
+				t.Errorf("expected replacement %t, got diff %+v", tt.replaced, diff)
+			}
+			if !tt.replaced && !diff.Empty() {
+				t.Errorf("expected no changes, got %+v", diff.Attributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package cern
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+const (
+	lowerChars   = "abcdefghijklmnopqrstuvwxyz"
+	upperChars   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numericChars = "0123456789"
+	specialChars = "!@#$%&*()-_=+[]{}<>:?"
+)
+
+func resourceTeigiGeneratedSecret() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTeigiGeneratedSecretCreate,
+		ReadContext:   resourceTeigiGeneratedSecretRead,
+		DeleteContext: resourceTeigiGeneratedSecretDelete,
+		CustomizeDiff: verifyTeigiHostDiff,
+
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Host where the secret is located",
+				ConflictsWith: []string{"hostgroup", "service"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"hostgroup": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Hostgroup where the secret is located",
+				ConflictsWith: []string{"host", "service"},
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return SerializeHostgroup(old) == SerializeHostgroup(new)
+				},
+			},
+
+			"service": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Service where the secret is located",
+				ConflictsWith: []string{"host", "hostgroup"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Key name under which the secret is stored",
+			},
+
+			"length": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      32,
+				Description:  "Number of characters, or bytes when 'random_bytes' is set, of the secret",
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+
+			"lower": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+				Description: "Include lowercase letters in the secret",
+			},
+
+			"upper": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+				Description: "Include uppercase letters in the secret",
+			},
+
+			"numeric": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+				Description: "Include digits in the secret",
+			},
+
+			"special": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     true,
+				Description: "Include special characters in the secret",
+			},
+
+			"override_special": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Special characters to use instead of the default ones",
+			},
+
+			"random_bytes": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  false,
+				Description: "Generate 'length' raw random bytes and store them base64 encoded, " +
+					"ignoring the character classes",
+			},
+
+			"keepers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Arbitrary values that generate a new secret when changed",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"base64_encoded": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether or not the secret is base64 encoded",
+			},
+
+			"secret_salt": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salt used to compute 'secret_hash'",
+			},
+
+			"secret_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salted SHA256 hash of the secret stored in Teigi",
+			},
+		},
+	}
+}
+
+// generateSecret generates a random secret following the policy of the
+// resource. The returned bool tells whether the secret is base64 encoded.
+func generateSecret(d *schema.ResourceData) (string, bool, error) {
+	length := d.Get("length").(int)
+
+	if d.Get("random_bytes").(bool) {
+		buf := make([]byte, length)
+		if _, err := rand.Read(buf); err != nil {
+			return "", false, err
+		}
+		return base64.StdEncoding.EncodeToString(buf), true, nil
+	}
+
+	special := specialChars
+	if v, ok := d.GetOk("override_special"); ok {
+		special = v.(string)
+	}
+
+	var charset string
+	for class, chars := range map[string]string{
+		"lower":   lowerChars,
+		"upper":   upperChars,
+		"numeric": numericChars,
+		"special": special,
+	} {
+		if d.Get(class).(bool) {
+			charset += chars
+		}
+	}
+	if charset == "" {
+		return "", false, fmt.Errorf("at least one character class must be enabled")
+	}
+
+	secret := make([]byte, length)
+	max := big.NewInt(int64(len(charset)))
+	for i := range secret {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", false, err
+		}
+		secret[i] = charset[n.Int64()]
+	}
+
+	return string(secret), false, nil
+}
+
+func resourceTeigiGeneratedSecretCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+
+	scope, entity, err := getScopeAndEntity(d)
+	if err != nil {
+		return diag.Errorf("Error getting scope and entity: %s", err)
+	}
+
+	secret, base64Encoded, err := generateSecret(d)
+	if err != nil {
+		return diag.Errorf("Unable to generate secret: %s", err)
+	}
+
+	key := d.Get("key").(string)
+	log.Printf("[DEBUG] Creating Teigi create request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	request := SecretRequest{
+		Secret: secret,
+	}
+	if base64Encoded {
+		request.Encoding = "b64"
+	}
+
+	err = client.Create(ctx, scope, entity, key, request)
+	if err != nil {
+		return diag.Errorf("Unable to create secret: %s", err)
+	}
+
+	d.SetId(scope + "/" + entity + "/" + key)
+	salt, err := generateSecretSalt()
+	if err != nil {
+		return diag.Errorf("Unable to generate salt: %s", err)
+	}
+	if err := d.Set("secret_salt", salt); err != nil {
+		return diag.Errorf("Unable to set secret_salt: %s", err)
+	}
+	if err := d.Set("secret_hash", saltedSecretHash(salt, secret)); err != nil {
+		return diag.Errorf("Unable to set secret_hash: %s", err)
+	}
+
+	return resourceTeigiGeneratedSecretRead(ctx, d, meta)
+}
+
+func resourceTeigiGeneratedSecretRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, key, err := parseTeigiSecretID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret id: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	secretResponse, err := client.Get(ctx, scope, entity, key)
+	if err != nil {
+		return diag.FromErr(CheckDeleted(d, "Unable to get secret", err))
+	}
+
+	// The secret was changed outside of Terraform, a new one is generated
+	// so that the stored value matches the policy again
+	if saltedSecretHash(d.Get("secret_salt").(string), secretResponse.Secret) != d.Get("secret_hash").(string) {
+		log.Printf("[WARN] Teigi secret %s was modified outside of Terraform, removing it from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	base64_encoded := secretResponse.Encoding == "b64"
+	if err := d.Set("base64_encoded", base64_encoded); err != nil {
+		return diag.Errorf("Unable to set base64_encoded: %s", err)
+	}
+	return nil
+}
+
+func resourceTeigiGeneratedSecretDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, key, err := parseTeigiSecretID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret id: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi delete request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	err = client.Delete(ctx, scope, entity, key)
+	if err != nil {
+		return diag.FromErr(CheckDeleted(d, "Unable to delete secret", err))
+	}
+
+	return nil
+}
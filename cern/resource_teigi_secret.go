@@ -2,11 +2,17 @@ package cern
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
@@ -18,6 +24,10 @@ func resourceTeigiSecret() *schema.Resource {
 		ReadContext:   resourceTeigiSecretRead,
 		UpdateContext: resourceTeigiSecretUpdate,
 		DeleteContext: resourceTeigiSecretDelete,
+		CustomizeDiff: customdiff.All(
+			verifyTeigiHostDiff,
+			resourceTeigiSecretCustomizeDiff,
+		),
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
@@ -67,10 +77,74 @@ func resourceTeigiSecret() *schema.Resource {
 			},
 
 			"secret": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        true,
+				Description:      "Secret string retrieved from Teigi",
+				DiffSuppressFunc: suppressHashedSecretDiff,
+				ExactlyOneOf:     []string{"secret", "secret_file", "content_base64"},
+			},
+
+			"secret_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Description: "Path of a file whose content is stored base64 encoded, " +
+					"changes of the content are tracked through 'content_sha256'",
+				ExactlyOneOf: []string{"secret", "secret_file", "content_base64"},
+			},
+
+			"content_base64": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+				Description: "Base64 encoded binary content of the secret, compared by its " +
+					"decoded bytes so that padding or line wrapping differences are ignored",
+				DiffSuppressFunc: suppressEquivalentBase64Diff,
+				ExactlyOneOf:     []string{"secret", "secret_file", "content_base64"},
+			},
+
+			"content_sha256": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Sensitive:   true,
-				Description: "Secret string retrieved from Teigi",
+				Computed:    true,
+				Description: "Salted SHA256 hash of the decoded content of the secret, using 'secret_salt'",
+			},
+
+			"store_secret_in_state": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+				Description: "Whether or not to keep the secret in the state. When false, " +
+					"only a salted hash of it is stored and used to detect changes",
+			},
+
+			"secret_salt": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salt used to compute 'secret_hash' and 'content_sha256'",
+			},
+
+			"secret_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salted SHA256 hash of the secret stored in Teigi",
+			},
+
+			"update_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Last time the secret was updated",
+			},
+
+			"update_time_str": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Last time the secret was updated, in human readable form",
+			},
+
+			"updated_by": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Last user who updated the secret",
 			},
 		},
 	}
@@ -85,6 +159,157 @@ func parseTeigiSecretID(id string) (string, string, string, error) {
 	return idParts[0], idParts[1], idParts[2], nil
 }
 
+// generateSecretSalt returns a random salt to hash the secrets kept out of
+// the state with
+func generateSecretSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// ensureSecretSalt sets 'secret_salt' to a new random salt when it is empty
+func ensureSecretSalt(d *schema.ResourceData) error {
+	if d.Get("secret_salt").(string) != "" {
+		return nil
+	}
+	salt, err := generateSecretSalt()
+	if err != nil {
+		return fmt.Errorf("Unable to generate salt: %s", err)
+	}
+	if err := d.Set("secret_salt", salt); err != nil {
+		return fmt.Errorf("Unable to set secret_salt: %s", err)
+	}
+	return nil
+}
+
+func saltedSecretHash(salt string, secret string) string {
+	sum := sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// suppressHashedSecretDiff hides the difference between the configured secret
+// and the empty one kept in the state when the secret is not stored in it,
+// as long as the hash of the configured secret matches the remote one
+func suppressHashedSecretDiff(k, old, new string, d *schema.ResourceData) bool {
+	if d.Get("store_secret_in_state").(bool) || d.Id() == "" {
+		return false
+	}
+
+	return saltedSecretHash(d.Get("secret_salt").(string), new) == d.Get("secret_hash").(string)
+}
+
+// decodeBase64 decodes standard or URL safe base64, with or without padding
+// and line wrapping
+func decodeBase64(encoded string) ([]byte, error) {
+	encoded = strings.Join(strings.Fields(encoded), "")
+	encoded = strings.TrimRight(encoded, "=")
+	decoded, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return base64.RawURLEncoding.DecodeString(encoded)
+	}
+	return decoded, nil
+}
+
+// suppressEquivalentBase64Diff hides the differences between base64 strings
+// that encode the same bytes
+func suppressEquivalentBase64Diff(k, old, new string, d *schema.ResourceData) bool {
+	newDecoded, err := decodeBase64(new)
+	if err != nil {
+		return false
+	}
+	if !d.Get("store_secret_in_state").(bool) && d.Id() != "" {
+		normalized := base64.StdEncoding.EncodeToString(newDecoded)
+		return saltedSecretHash(d.Get("secret_salt").(string), normalized) == d.Get("secret_hash").(string)
+	}
+
+	oldDecoded, err := decodeBase64(old)
+	if err != nil {
+		return false
+	}
+	return string(oldDecoded) == string(newDecoded)
+}
+
+// verifyTeigiHostDiff checks at plan time that the host of a Teigi secret
+// exists in the DNS, when the provider is set to only verify hosts then. Hosts
+// only known after apply are not verified, so secrets can be created along
+// with their machine.
+func verifyTeigiHostDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	hosts := meta.(*config).TeigiClient.Hosts
+	if hosts.Verification != HostVerificationPlan || !d.NewValueKnown("host") || !d.HasChange("host") {
+		return nil
+	}
+
+	host := d.Get("host").(string)
+	if host == "" {
+		return nil
+	}
+	if err := hosts.Verify(ctx, host); err != nil {
+		return fmt.Errorf("Unable to verify host %s: %s", host, err)
+	}
+	return nil
+}
+
+// resourceTeigiSecretCustomizeDiff plans an update whenever the content of
+// 'secret_file' differs from the one stored in Teigi
+func resourceTeigiSecretCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	path := d.Get("secret_file").(string)
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read secret_file: %s", err)
+	}
+
+	// The hash is salted like 'secret_hash', the salt is only known once
+	// the secret is read
+	salt := d.Get("secret_salt").(string)
+	if salt == "" {
+		return d.SetNewComputed("content_sha256")
+	}
+	if hash := saltedSecretHash(salt, string(content)); hash != d.Get("content_sha256").(string) {
+		return d.SetNew("content_sha256", hash)
+	}
+	return nil
+}
+
+// teigiSecretRequest builds the Teigi request out of whichever of 'secret',
+// 'secret_file' or 'content_base64' is set
+func teigiSecretRequest(d *schema.ResourceData) (SecretRequest, error) {
+	if path := d.Get("secret_file").(string); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return SecretRequest{}, fmt.Errorf("Unable to read secret_file: %s", err)
+		}
+		return SecretRequest{
+			Secret:   base64.StdEncoding.EncodeToString(content),
+			Encoding: "b64",
+		}, nil
+	}
+
+	if encoded := d.Get("content_base64").(string); encoded != "" {
+		content, err := decodeBase64(encoded)
+		if err != nil {
+			return SecretRequest{}, fmt.Errorf("Unable to decode content_base64: %s", err)
+		}
+		return SecretRequest{
+			Secret:   base64.StdEncoding.EncodeToString(content),
+			Encoding: "b64",
+		}, nil
+	}
+
+	request := SecretRequest{
+		Secret: d.Get("secret").(string),
+	}
+	if d.Get("base64_encoded").(bool) {
+		request.Encoding = "b64"
+	}
+	return request, nil
+}
+
 func resourceTeigiSecretCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	client := meta.(*config).TeigiClient
 
@@ -96,11 +321,9 @@ func resourceTeigiSecretCreate(ctx context.Context, d *schema.ResourceData, meta
 	key := d.Get("key").(string)
 	log.Printf("[DEBUG] Creating Teigi create request for %s scope and %s entity for %s key",
 		scope, entity, key)
-	request := SecretRequest{
-		Secret: d.Get("secret").(string),
-	}
-	if d.Get("base64_encoded").(bool) {
-		request.Encoding = "b64"
+	request, err := teigiSecretRequest(d)
+	if err != nil {
+		return diag.FromErr(err)
 	}
 
 	err = client.Create(ctx, scope, entity, key, request)
@@ -133,15 +356,68 @@ func resourceTeigiSecretRead(ctx context.Context, d *schema.ResourceData, meta i
 		return diag.Errorf("Unable to set key: %s", err)
 	}
 
-	if err := d.Set("secret", secretResponse.Secret); err != nil {
-		return diag.Errorf("Unable to set secret: %s", err)
+	// The salt is generated on the first read, which also covers the
+	// imported secrets
+	if err := ensureSecretSalt(d); err != nil {
+		return diag.FromErr(err)
+	}
+	secret := secretResponse.Secret
+	secretHash := saltedSecretHash(d.Get("secret_salt").(string), secret)
+	if err := d.Set("secret_hash", secretHash); err != nil {
+		return diag.Errorf("Unable to set secret_hash: %s", err)
+	}
+	if !d.Get("store_secret_in_state").(bool) {
+		secret = ""
+	}
+
+	// The secret is only stored in the attribute used to configure it
+	switch {
+	case d.Get("secret_file").(string) != "":
+	case d.Get("content_base64").(string) != "":
+		if err := d.Set("content_base64", secret); err != nil {
+			return diag.Errorf("Unable to set content_base64: %s", err)
+		}
+	default:
+		if err := d.Set("secret", secret); err != nil {
+			return diag.Errorf("Unable to set secret: %s", err)
+		}
+	}
+
+	content := []byte(secretResponse.Secret)
+	if secretResponse.Encoding == "b64" {
+		if decoded, err := decodeBase64(secretResponse.Secret); err == nil {
+			content = decoded
+		}
+	}
+	if err := d.Set("content_sha256", saltedSecretHash(d.Get("secret_salt").(string), string(content))); err != nil {
+		return diag.Errorf("Unable to set content_sha256: %s", err)
+	}
+	if err := d.Set("update_time", secretResponse.UpdateTime); err != nil {
+		return diag.Errorf("Unable to set update_time: %s", err)
+	}
+	if err := d.Set("update_time_str", secretResponse.UpdateTimeStr); err != nil {
+		return diag.Errorf("Unable to set update_time_str: %s", err)
+	}
+	if err := d.Set("updated_by", secretResponse.UpdatedBy); err != nil {
+		return diag.Errorf("Unable to set updated_by: %s", err)
 	}
 
 	base64_encoded := secretResponse.Encoding == "b64"
 	if err := d.Set("base64_encoded", base64_encoded); err != nil {
 		return diag.Errorf("Unable to set base64_encoded: %s", err)
 	}
-	return nil
+
+	var diags diag.Diagnostics
+	principal := meta.(*config).Principal
+	if principal != "" && secretResponse.UpdatedBy != "" && !isPrincipal(secretResponse.UpdatedBy, principal) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Teigi secret %s was modified outside of Terraform", d.Id()),
+			Detail: fmt.Sprintf("The secret was last updated by %s on %s, while Terraform runs as %s.",
+				secretResponse.UpdatedBy, secretResponse.UpdateTimeStr, principal),
+		})
+	}
+	return diags
 }
 
 func resourceTeigiSecretUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -0,0 +1,299 @@
+package cern
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceTeigiSecretCopy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTeigiSecretCopyCreate,
+		ReadContext:   resourceTeigiSecretCopyRead,
+		UpdateContext: resourceTeigiSecretCopyUpdate,
+		DeleteContext: resourceTeigiSecretCopyDelete,
+		CustomizeDiff: customdiff.All(
+			verifyTeigiHostDiff,
+			resourceTeigiSecretCopyCustomizeDiff,
+		),
+
+		Schema: map[string]*schema.Schema{
+			"source_host": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Host where the source secret is located",
+				ConflictsWith: []string{"source_hostgroup", "source_service"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"source_hostgroup": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Hostgroup where the source secret is located",
+				ConflictsWith: []string{"source_host", "source_service"},
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return SerializeHostgroup(old) == SerializeHostgroup(new)
+				},
+			},
+
+			"source_service": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Service where the source secret is located",
+				ConflictsWith: []string{"source_host", "source_hostgroup"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"source_key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Key name of the source secret",
+			},
+
+			"host": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Host where the secret is copied",
+				ConflictsWith: []string{"hostgroup", "service"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"hostgroup": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Hostgroup where the secret is copied",
+				ConflictsWith: []string{"host", "service"},
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return SerializeHostgroup(old) == SerializeHostgroup(new)
+				},
+			},
+
+			"service": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Service where the secret is copied",
+				ConflictsWith: []string{"host", "hostgroup"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"key": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Key name of the copied secret",
+			},
+
+			"sync": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+				Description: "Whether or not to copy the source secret again on every apply " +
+					"when it changes, instead of copying it only once",
+			},
+
+			"delete_source": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether or not to delete the source secret once it is copied",
+			},
+
+			"secret_salt": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salt used to compute 'source_hash' and 'secret_hash'",
+			},
+
+			"source_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salted SHA256 hash of the source secret, empty once it is deleted",
+			},
+
+			"secret_hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Salted SHA256 hash of the copied secret",
+			},
+		},
+	}
+}
+
+func getSourceScopeAndEntity(d *schema.ResourceData) (string, string, error) {
+	return scopeAndEntity(d.Get("source_host").(string), d.Get("source_hostgroup").(string), d.Get("source_service").(string))
+}
+
+// copyTeigiSecret copies the source secret to the destination, deleting the
+// source afterwards if requested
+func copyTeigiSecret(ctx context.Context, d *schema.ResourceData, client *Teigi) error {
+	sourceScope, sourceEntity, err := getSourceScopeAndEntity(d)
+	if err != nil {
+		return fmt.Errorf("Error getting source scope and entity: %s", err)
+	}
+	scope, entity, err := getScopeAndEntity(d)
+	if err != nil {
+		return fmt.Errorf("Error getting scope and entity: %s", err)
+	}
+	sourceKey := d.Get("source_key").(string)
+	key := d.Get("key").(string)
+
+	log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+		sourceScope, sourceEntity, sourceKey)
+	secretResponse, err := client.Get(ctx, sourceScope, sourceEntity, sourceKey)
+	if err != nil {
+		return fmt.Errorf("Unable to get source secret: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi create request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	err = client.Create(ctx, scope, entity, key, secretResponse.SecretRequest)
+	if err != nil {
+		return fmt.Errorf("Unable to create secret: %s", err)
+	}
+
+	if d.Get("delete_source").(bool) {
+		if err := deleteTeigiSourceSecret(ctx, d, client); err != nil {
+			return err
+		}
+	}
+
+	d.SetId(scope + "/" + entity + "/" + key)
+	return nil
+}
+
+// deleteTeigiSourceSecret deletes the source secret, ignoring it when it is
+// already gone
+func deleteTeigiSourceSecret(ctx context.Context, d *schema.ResourceData, client *Teigi) error {
+	sourceScope, sourceEntity, err := getSourceScopeAndEntity(d)
+	if err != nil {
+		return fmt.Errorf("Error getting source scope and entity: %s", err)
+	}
+	sourceKey := d.Get("source_key").(string)
+
+	log.Printf("[DEBUG] Creating Teigi delete request for %s scope and %s entity for %s key",
+		sourceScope, sourceEntity, sourceKey)
+	err = client.Delete(ctx, sourceScope, sourceEntity, sourceKey)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("Unable to delete source secret: %s", err)
+	}
+	return nil
+}
+
+// resourceTeigiSecretCopyCustomizeDiff plans a new copy when the source secret
+// changed since the last one, as long as it is kept in sync
+func resourceTeigiSecretCopyCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.Get("sync").(bool) {
+		return nil
+	}
+
+	sourceHash := d.Get("source_hash").(string)
+	if sourceHash != "" && sourceHash != d.Get("secret_hash").(string) {
+		return d.SetNew("secret_hash", sourceHash)
+	}
+	return nil
+}
+
+func resourceTeigiSecretCopyCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+
+	if err := ensureSecretSalt(d); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := copyTeigiSecret(ctx, d, client); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceTeigiSecretCopyRead(ctx, d, meta)
+}
+
+func resourceTeigiSecretCopyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, key, err := parseTeigiSecretID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret id: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	secretResponse, err := client.Get(ctx, scope, entity, key)
+	if err != nil {
+		return diag.FromErr(CheckDeleted(d, "Unable to get secret", err))
+	}
+	salt := d.Get("secret_salt").(string)
+	if err := d.Set("secret_hash", saltedSecretHash(salt, secretResponse.Secret)); err != nil {
+		return diag.Errorf("Unable to set secret_hash: %s", err)
+	}
+
+	// The source is only looked at when the copy is kept in sync, and may
+	// well be gone after being promoted
+	sourceHash := ""
+	if d.Get("sync").(bool) {
+		sourceScope, sourceEntity, err := getSourceScopeAndEntity(d)
+		if err != nil {
+			return diag.Errorf("Error getting source scope and entity: %s", err)
+		}
+		sourceKey := d.Get("source_key").(string)
+
+		log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+			sourceScope, sourceEntity, sourceKey)
+		sourceResponse, err := client.Get(ctx, sourceScope, sourceEntity, sourceKey)
+		if err == nil {
+			sourceHash = saltedSecretHash(salt, sourceResponse.Secret)
+		} else if !IsNotFound(err) {
+			return diag.Errorf("Unable to get source secret: %s", err)
+		}
+	}
+	if err := d.Set("source_hash", sourceHash); err != nil {
+		return diag.Errorf("Unable to set source_hash: %s", err)
+	}
+
+	return nil
+}
+
+func resourceTeigiSecretCopyUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+
+	// The source is copied again only when it changed, since it may already
+	// be gone when 'delete_source' is turned on
+	switch {
+	case d.HasChange("secret_hash"):
+		if err := copyTeigiSecret(ctx, d, client); err != nil {
+			return diag.FromErr(err)
+		}
+	case d.HasChange("delete_source") && d.Get("delete_source").(bool):
+		if err := deleteTeigiSourceSecret(ctx, d, client); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceTeigiSecretCopyRead(ctx, d, meta)
+}
+
+func resourceTeigiSecretCopyDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, key, err := parseTeigiSecretID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret id: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi delete request for %s scope and %s entity for %s key",
+		scope, entity, key)
+	err = client.Delete(ctx, scope, entity, key)
+	if err != nil {
+		return diag.FromErr(CheckDeleted(d, "Unable to delete secret", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package cern
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceTeigiSecretSet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTeigiSecretSetCreate,
+		ReadContext:   resourceTeigiSecretSetRead,
+		UpdateContext: resourceTeigiSecretSetUpdate,
+		DeleteContext: resourceTeigiSecretSetDelete,
+		CustomizeDiff: verifyTeigiHostDiff,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceTeigiSecretSetImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Host where the secrets are located",
+				ConflictsWith: []string{"hostgroup", "service"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"hostgroup": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Hostgroup where the secrets are located",
+				ConflictsWith: []string{"host", "service"},
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return SerializeHostgroup(old) == SerializeHostgroup(new)
+				},
+			},
+
+			"service": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				Description:   "Service where the secrets are located",
+				ConflictsWith: []string{"host", "hostgroup"},
+				ValidateFunc:  validation.StringDoesNotContainAny("/"),
+			},
+
+			"secrets": {
+				Type:        schema.TypeMap,
+				Required:    true,
+				Sensitive:   true,
+				Description: "Secrets stored under the entity, indexed by key name",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"base64_encoded": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether or not the secrets are base64 encoded",
+			},
+
+			"remove_unlisted": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Whether or not to remove the secrets of the entity " +
+					"which are not listed in 'secrets'",
+			},
+		},
+	}
+}
+
+func parseTeigiSecretSetID(id string) (string, string, error) {
+	idParts := strings.Split(id, "/")
+	if len(idParts) != 2 {
+		return "", "", fmt.Errorf("Unable to determine teigi secret set ID %s", id)
+	}
+
+	return idParts[0], idParts[1], nil
+}
+
+// setTeigiSecrets creates or updates the given secrets of an entity
+func setTeigiSecrets(ctx context.Context, d *schema.ResourceData, client *Teigi, scope string, entity string, secrets map[string]interface{}) error {
+	for key, secret := range secrets {
+		log.Printf("[DEBUG] Creating Teigi create request for %s scope and %s entity for %s key",
+			scope, entity, key)
+		request := SecretRequest{
+			Secret: secret.(string),
+		}
+		if d.Get("base64_encoded").(bool) {
+			request.Encoding = "b64"
+		}
+
+		if err := client.Create(ctx, scope, entity, key, request); err != nil {
+			return fmt.Errorf("Unable to create secret %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+// deleteTeigiSecrets deletes the given keys of an entity, ignoring the ones
+// that are already gone
+func deleteTeigiSecrets(ctx context.Context, client *Teigi, scope string, entity string, keys []string) error {
+	for _, key := range keys {
+		log.Printf("[DEBUG] Creating Teigi delete request for %s scope and %s entity for %s key",
+			scope, entity, key)
+		if err := client.Delete(ctx, scope, entity, key); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("Unable to delete secret %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func resourceTeigiSecretSetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+
+	scope, entity, err := getScopeAndEntity(d)
+	if err != nil {
+		return diag.Errorf("Error getting scope and entity: %s", err)
+	}
+
+	secrets := d.Get("secrets").(map[string]interface{})
+	if err := setTeigiSecrets(ctx, d, client, scope, entity, secrets); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The keys already in the entity which are not listed are removed right
+	// away, instead of on the next apply
+	if d.Get("remove_unlisted").(bool) {
+		log.Printf("[DEBUG] Creating Teigi list request for %s scope and %s entity", scope, entity)
+		keys, err := client.List(ctx, scope, entity)
+		if err != nil && !IsNotFound(err) {
+			return diag.Errorf("Unable to list secrets: %s", err)
+		}
+
+		var unlisted []string
+		for _, key := range keys {
+			if _, ok := secrets[key]; !ok {
+				unlisted = append(unlisted, key)
+			}
+		}
+		if err := deleteTeigiSecrets(ctx, client, scope, entity, unlisted); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(scope + "/" + entity)
+	return resourceTeigiSecretSetRead(ctx, d, meta)
+}
+
+func resourceTeigiSecretSetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, err := parseTeigiSecretSetID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret set id: %s", err)
+	}
+
+	// Only the managed keys are refreshed, unless the unlisted keys have to
+	// be removed
+	var keys []string
+	secrets := d.Get("secrets").(map[string]interface{})
+	if d.Get("remove_unlisted").(bool) {
+		log.Printf("[DEBUG] Creating Teigi list request for %s scope and %s entity", scope, entity)
+		keys, err = client.List(ctx, scope, entity)
+		if err != nil {
+			return diag.FromErr(CheckDeleted(d, "Unable to list secrets", err))
+		}
+	}
+	for key := range secrets {
+		if _, found := find(keys, key); !found {
+			keys = append(keys, key)
+		}
+	}
+
+	responses := make(map[string]*SecretResponse)
+	encodings := make(map[bool]bool)
+	for _, key := range keys {
+		log.Printf("[DEBUG] Creating Teigi read request for %s scope and %s entity for %s key",
+			scope, entity, key)
+		secretResponse, err := client.Get(ctx, scope, entity, key)
+		if IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return diag.Errorf("Unable to get secret %s: %s", key, err)
+		}
+		responses[key] = secretResponse
+		encodings[secretResponse.Encoding == "b64"] = true
+	}
+
+	// The encoding is only taken from Teigi when every key agrees on it. With
+	// mixed encodings, the managed keys which do not use the configured one
+	// are left out so that only those are written again.
+	base64Encoded := d.Get("base64_encoded").(bool)
+	if len(encodings) == 1 {
+		for encoded := range encodings {
+			base64Encoded = encoded
+		}
+	}
+	remoteSecrets := make(map[string]interface{})
+	for key, secretResponse := range responses {
+		if _, managed := secrets[key]; managed && (secretResponse.Encoding == "b64") != base64Encoded {
+			log.Printf("[WARN] Teigi secret %s of %s/%s does not use the configured encoding", key, scope, entity)
+			continue
+		}
+		remoteSecrets[key] = secretResponse.Secret
+	}
+
+	if err := d.Set(scope, entity); err != nil {
+		return diag.Errorf("Unable to set '%s': %s", scope, err)
+	}
+	if err := d.Set("secrets", remoteSecrets); err != nil {
+		return diag.Errorf("Unable to set secrets: %s", err)
+	}
+	if err := d.Set("base64_encoded", base64Encoded); err != nil {
+		return diag.Errorf("Unable to set base64_encoded: %s", err)
+	}
+	return nil
+}
+
+// resourceTeigiSecretSetImport manages every key of the imported entity, the
+// following reads only look at the managed ones
+func resourceTeigiSecretSetImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*config).TeigiClient
+	scope, entity, err := parseTeigiSecretSetID(d.Id())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse teigi secret set id: %s", err)
+	}
+
+	log.Printf("[DEBUG] Creating Teigi list request for %s scope and %s entity", scope, entity)
+	keys, err := client.List(ctx, scope, entity)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list secrets: %s", err)
+	}
+
+	secrets := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		secrets[key] = ""
+	}
+	if err := d.Set("secrets", secrets); err != nil {
+		return nil, fmt.Errorf("Unable to set secrets: %s", err)
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceTeigiSecretSetUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, err := parseTeigiSecretSetID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret set id: %s", err)
+	}
+
+	o, n := d.GetChange("secrets")
+	oldSecrets := o.(map[string]interface{})
+	newSecrets := n.(map[string]interface{})
+
+	// Every key is rewritten when the encoding changes, otherwise only the
+	// ones whose value changed
+	changed := make(map[string]interface{})
+	for key, secret := range newSecrets {
+		if oldSecret, ok := oldSecrets[key]; !ok || oldSecret != secret || d.HasChange("base64_encoded") {
+			changed[key] = secret
+		}
+	}
+	if err := setTeigiSecrets(ctx, d, client, scope, entity, changed); err != nil {
+		return diag.FromErr(err)
+	}
+
+	var removed []string
+	for key := range oldSecrets {
+		if _, ok := newSecrets[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	if err := deleteTeigiSecrets(ctx, client, scope, entity, removed); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceTeigiSecretSetRead(ctx, d, meta)
+}
+
+func resourceTeigiSecretSetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*config).TeigiClient
+	scope, entity, err := parseTeigiSecretSetID(d.Id())
+	if err != nil {
+		return diag.Errorf("Unable to parse teigi secret set id: %s", err)
+	}
+
+	var keys []string
+	for key := range d.Get("secrets").(map[string]interface{}) {
+		keys = append(keys, key)
+	}
+	if err := deleteTeigiSecrets(ctx, client, scope, entity, keys); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
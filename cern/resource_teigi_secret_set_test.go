@@ -0,0 +1,193 @@
+package cern
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+// newTestTeigiSecretSet returns the data of a secret set managing the given
+// secrets of the myservice service
+func newTestTeigiSecretSet(t *testing.T, secrets map[string]interface{}, removeUnlisted bool) *schema.ResourceData {
+	t.Helper()
+
+	return newTestTeigiSecretSetRaw(t, map[string]interface{}{
+		"service":         "myservice",
+		"secrets":         secrets,
+		"remove_unlisted": removeUnlisted,
+	})
+}
+
+// newTestTeigiSecretSetRaw returns the data of a secret set of the myservice
+// service with the given configuration
+func newTestTeigiSecretSetRaw(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	t.Helper()
+
+	d := schema.TestResourceDataRaw(t, resourceTeigiSecretSet().Schema, raw)
+	d.SetId("service/myservice")
+	return d
+}
+
+func TestTeigiSecretSetRead(t *testing.T) {
+	tests := []struct {
+		name           string
+		secrets        map[string]interface{}
+		removeUnlisted bool
+		expected       map[string]interface{}
+	}{
+		{
+			name:     "managed keys",
+			secrets:  map[string]interface{}{"managed": "old"},
+			expected: map[string]interface{}{"managed": "value"},
+		},
+		{
+			name:     "no managed keys",
+			secrets:  map[string]interface{}{},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:           "unlisted keys to remove",
+			secrets:        map[string]interface{}{"managed": "value"},
+			removeUnlisted: true,
+			expected:       map[string]interface{}{"managed": "value", "unmanaged": "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestTeigi(t, teigiEntityHandler(map[string]SecretRequest{
+				"managed":   {Secret: "value"},
+				"unmanaged": {Secret: "other"},
+			}))
+			d := newTestTeigiSecretSet(t, tt.secrets, tt.removeUnlisted)
+
+			if diags := resourceTeigiSecretSetRead(context.Background(), d, &config{TeigiClient: client}); diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if secrets := d.Get("secrets").(map[string]interface{}); !reflect.DeepEqual(secrets, tt.expected) {
+				t.Errorf("expected secrets %v, got %v", tt.expected, secrets)
+			}
+		})
+	}
+}
+
+func TestTeigiSecretSetReadEncodings(t *testing.T) {
+	tests := []struct {
+		name          string
+		base64Encoded bool
+		remote        map[string]SecretRequest
+		expected      map[string]interface{}
+		expectedB64   bool
+	}{
+		{
+			name: "same encoding as configured",
+			remote: map[string]SecretRequest{
+				"first":  {Secret: "one"},
+				"second": {Secret: "two"},
+			},
+			expected: map[string]interface{}{"first": "one", "second": "two"},
+		},
+		{
+			name: "every key with another encoding",
+			remote: map[string]SecretRequest{
+				"first":  {Secret: "b25l", Encoding: "b64"},
+				"second": {Secret: "dHdv", Encoding: "b64"},
+			},
+			expected:    map[string]interface{}{"first": "b25l", "second": "dHdv"},
+			expectedB64: true,
+		},
+		{
+			name:          "mixed encodings",
+			base64Encoded: true,
+			remote: map[string]SecretRequest{
+				"first":  {Secret: "one"},
+				"second": {Secret: "dHdv", Encoding: "b64"},
+			},
+			expected:    map[string]interface{}{"second": "dHdv"},
+			expectedB64: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestTeigi(t, teigiEntityHandler(tt.remote))
+			d := newTestTeigiSecretSetRaw(t, map[string]interface{}{
+				"service":        "myservice",
+				"secrets":        map[string]interface{}{"first": "", "second": ""},
+				"base64_encoded": tt.base64Encoded,
+			})
+
+			if diags := resourceTeigiSecretSetRead(context.Background(), d, &config{TeigiClient: client}); diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if secrets := d.Get("secrets").(map[string]interface{}); !reflect.DeepEqual(secrets, tt.expected) {
+				t.Errorf("expected secrets %v, got %v", tt.expected, secrets)
+			}
+			if encoded := d.Get("base64_encoded").(bool); encoded != tt.expectedB64 {
+				t.Errorf("expected base64_encoded %t, got %t", tt.expectedB64, encoded)
+			}
+		})
+	}
+}
+
+func TestTeigiSecretSetUpdateKeepsUnmanagedKeys(t *testing.T) {
+	remote := map[string]SecretRequest{
+		"unmanaged": {Secret: "other"},
+	}
+	client, _ := newTestTeigi(t, teigiEntityHandler(remote))
+	meta := &config{TeigiClient: client}
+	r := resourceTeigiSecretSet()
+
+	// Every managed key was deleted outside of Terraform, which leaves an
+	// empty map in the state after a refresh
+	state := newTestTeigiSecretSet(t, map[string]interface{}{}, false).State()
+	state, diags := r.RefreshWithoutUpgrade(context.Background(), state, meta)
+	if diags.HasError() {
+		t.Fatalf("unexpected refresh diagnostics: %v", diags)
+	}
+
+	// The plan then only adds the configured key
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"service": "myservice",
+		"secrets": map[string]interface{}{"managed": "value"},
+	}), meta)
+	if err != nil {
+		t.Fatalf("unexpected diff error: %s", err)
+	}
+	if _, diags := r.Apply(context.Background(), state, diff, meta); diags.HasError() {
+		t.Fatalf("unexpected apply diagnostics: %v", diags)
+	}
+
+	expected := map[string]SecretRequest{
+		"managed":   {Secret: "value"},
+		"unmanaged": {Secret: "other"},
+	}
+	if !reflect.DeepEqual(remote, expected) {
+		t.Errorf("expected the remote secrets %v, got %v", expected, remote)
+	}
+}
+
+func TestTeigiSecretSetImport(t *testing.T) {
+	client, _ := newTestTeigi(t, teigiEntityHandler(map[string]SecretRequest{
+		"first":  {Secret: "one"},
+		"second": {Secret: "two"},
+	}))
+	meta := &config{TeigiClient: client}
+
+	d := resourceTeigiSecretSet().TestResourceData()
+	d.SetId("service/myservice")
+	if _, err := resourceTeigiSecretSetImport(context.Background(), d, meta); err != nil {
+		t.Fatalf("unexpected import error: %s", err)
+	}
+	if diags := resourceTeigiSecretSetRead(context.Background(), d, meta); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	expected := map[string]interface{}{"first": "one", "second": "two"}
+	if secrets := d.Get("secrets").(map[string]interface{}); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("expected secrets %v, got %v", expected, secrets)
+	}
+}
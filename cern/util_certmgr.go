@@ -9,13 +9,15 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/dpotapov/go-spnego"
 )
 
 // Certmr client manages requests to the Certmgr service
 type CertMgr struct {
-	URL *url.URL
+	URL        *url.URL
+	HTTPClient *http.Client
 }
 
 type CertMgrRequest struct {
@@ -29,6 +31,22 @@ type CertMgrResponse struct {
 	End       string `json:"End"`
 }
 
+// EndTime parses the end of the staging, returning false when it cannot be
+func (r CertMgrResponse) EndTime() (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if end, err := time.Parse(layout, r.End); err == nil {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Expired returns true when the end of the staging is in the past
+func (r CertMgrResponse) Expired(now time.Time) bool {
+	end, ok := r.EndTime()
+	return ok && end.Before(now)
+}
+
 // NewCertMgrClient constructs a new client configuration
 func NewCertMgrClient(endpoint string) (*CertMgr, error) {
 	url, err := url.Parse(endpoint)
@@ -44,15 +62,14 @@ func NewCertMgrClient(endpoint string) (*CertMgr, error) {
 
 	return &CertMgr{
 		URL: url,
+		HTTPClient: &http.Client{
+			Transport: &spnego.Transport{},
+		},
 	}, nil
 }
 
 // Get a new certficiate for a given host
 func (c CertMgr) Do(ctx context.Context, hostname string) (*CertMgrResponse, error) {
-	client := http.Client{
-		Transport: &spnego.Transport{},
-	}
-
 	url := fmt.Sprintf("%s/krb/certmgr/staged/", c.URL)
 	log.Printf("[DEBUG] Request url constructed as follows: %s", url)
 
@@ -60,27 +77,31 @@ func (c CertMgr) Do(ctx context.Context, hostname string) (*CertMgrResponse, err
 		Hostname: hostname,
 	})
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestData))
+	body, err := c.request(ctx, "POST", url, requestData)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	var certMgrResponse CertMgrResponse
+	err = json.Unmarshal(body, &certMgrResponse)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 && resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("non 2xx error code (%d)", resp.StatusCode)
-	}
+	return &certMgrResponse, nil
+}
 
-	var certMgrResponse CertMgrResponse
-	body, err := ioutil.ReadAll(resp.Body)
+// Get the staged certificate with the given id
+func (c CertMgr) Get(ctx context.Context, id int) (*CertMgrResponse, error) {
+	url := fmt.Sprintf("%s/krb/certmgr/staged/%d/", c.URL, id)
+	log.Printf("[DEBUG] Request url constructed as follows: %s", url)
+
+	body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	var certMgrResponse CertMgrResponse
 	err = json.Unmarshal(body, &certMgrResponse)
 	if err != nil {
 		return nil, err
@@ -88,3 +109,60 @@ func (c CertMgr) Do(ctx context.Context, hostname string) (*CertMgrResponse, err
 
 	return &certMgrResponse, nil
 }
+
+// Unstage the certificate with the given id
+func (c CertMgr) Unstage(ctx context.Context, id int) error {
+	url := fmt.Sprintf("%s/krb/certmgr/staged/%d/", c.URL, id)
+	log.Printf("[DEBUG] Request url constructed as follows: %s", url)
+
+	_, err := c.request(ctx, "DELETE", url, nil)
+	return err
+}
+
+// List the staged certificates of a given host
+func (c CertMgr) List(ctx context.Context, hostname string) ([]CertMgrResponse, error) {
+	listURL := fmt.Sprintf("%s/krb/certmgr/staged/?hostname=%s", c.URL, url.QueryEscape(hostname))
+	log.Printf("[DEBUG] Request url constructed as follows: %s", listURL)
+
+	body, err := c.request(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var certMgrResponses []CertMgrResponse
+	err = json.Unmarshal(body, &certMgrResponses)
+	if err != nil {
+		return nil, err
+	}
+
+	return certMgrResponses, nil
+}
+
+// request sends a request to Certmgr and returns the body of the response
+func (c CertMgr) request(ctx context.Context, method string, url string, requestData []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestData))
+	if err != nil {
+		return nil, err
+	}
+	if requestData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, HTTPError{URL: url, StatusCode: resp.StatusCode, RespBody: string(body[:])}
+	}
+
+	return body, nil
+}
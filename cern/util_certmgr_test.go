@@ -0,0 +1,97 @@
+package cern
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// newTestCertMgr returns a CertMgr client talking to a stand-in of the
+// Certmgr API which answers every request with the given status
+func newTestCertMgr(t *testing.T, status int) (*CertMgr, func() []string) {
+	t.Helper()
+
+	serverURL, client, requests := newTestServer(t, statusHandler(status, ""))
+	return &CertMgr{
+		URL:        serverURL,
+		HTTPClient: client,
+	}, requests
+}
+
+func TestCertMgrUnstage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		fails  bool
+	}{
+		{name: "unstaged", status: http.StatusNoContent},
+		{name: "not found", status: http.StatusNotFound, fails: true},
+		{name: "server error", status: http.StatusInternalServerError, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newTestCertMgr(t, tt.status)
+
+			err := client.Unstage(context.Background(), 42)
+			if (err != nil) != tt.fails {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil && !hasStatusCode(err, tt.status) {
+				t.Errorf("expected a %d HTTPError, got %s", tt.status, err)
+			}
+
+			checkRequests(t, requests, "DELETE /krb/certmgr/staged/42/")
+		})
+	}
+}
+
+func TestCertMgrDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		fails  bool
+	}{
+		{name: "unstaged", status: http.StatusNoContent},
+		{name: "already unstaged", status: http.StatusNotFound},
+		{name: "already consumed", status: http.StatusGone},
+		{name: "server error", status: http.StatusInternalServerError, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newTestCertMgr(t, tt.status)
+
+			d := schema.TestResourceDataRaw(t, certMgrResource().Schema, map[string]interface{}{
+				"hostname": "myhost.cern.ch",
+			})
+			d.SetId("myhost.cern.ch")
+			if err := d.Set("id_cert", 42); err != nil {
+				t.Fatal(err)
+			}
+
+			diags := resourceCertMgrDelete(context.Background(), d, &config{CertMgrClient: client})
+			if diags.HasError() != tt.fails {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			checkRequests(t, requests, "DELETE /krb/certmgr/staged/42/")
+		})
+	}
+}
+
+func TestCertMgrDeleteWithoutStaging(t *testing.T) {
+	client, requests := newTestCertMgr(t, http.StatusInternalServerError)
+
+	d := schema.TestResourceDataRaw(t, certMgrResource().Schema, map[string]interface{}{
+		"hostname": "myhost.cern.ch",
+	})
+	d.SetId("myhost.cern.ch")
+
+	if diags := resourceCertMgrDelete(context.Background(), d, &config{CertMgrClient: client}); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	checkRequests(t, requests)
+}
@@ -1,23 +1,35 @@
 package cern
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"net/http"
 	"net/url"
+	"os"
+	"os/user"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/jcmturner/gokrb5/v8/credentials"
 )
 
 type HTTPError struct {
 	URL        string
 	StatusCode int
 	RespBody   string
+	// Message is the human readable error extracted from RespBody, if any
+	Message string
 }
 
 func (e HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%d %s: %s (url: %s)",
+			e.StatusCode, http.StatusText(e.StatusCode), e.Message, e.URL)
+	}
 	return fmt.Sprintf(
 		"HTTP Error:{\n"+
 			"  url:        [%s]\n"+
@@ -30,17 +42,90 @@ func (e HTTPError) Error() string {
 	)
 }
 
+// errorResponse defines the error bodies returned by the CERN APIs. Depending
+// on the layer that rejects the request the text is found in a different field.
+type errorResponse struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Detail  string `json:"detail"`
+}
+
+// newHTTPError builds an HTTPError out of a non 2xx response, turning the JSON
+// error body into a readable message when possible. notFoundMessage is used
+// for the 404 responses without one.
+func newHTTPError(url string, statusCode int, body []byte, notFoundMessage string) HTTPError {
+	httpError := HTTPError{
+		URL:        url,
+		StatusCode: statusCode,
+		RespBody:   string(body[:]),
+	}
+
+	var response errorResponse
+	if err := json.Unmarshal(body, &response); err == nil {
+		for _, message := range []string{response.Message, response.Error, response.Detail} {
+			if message != "" {
+				httpError.Message = message
+				break
+			}
+		}
+	}
+
+	switch {
+	case IsAuthError(httpError):
+		if httpError.Message == "" {
+			httpError.Message = "access denied"
+		}
+		httpError.Message += ", make sure a valid Kerberos ticket is available and it is allowed to make this request"
+	case IsNotFound(httpError) && httpError.Message == "":
+		httpError.Message = notFoundMessage
+	}
+
+	return httpError
+}
+
+// hasStatusCode checks whether err is an HTTPError with one of the given
+// status codes
+func hasStatusCode(err error, codes ...int) bool {
+	var httpError HTTPError
+	if !errors.As(err, &httpError) {
+		return false
+	}
+	for _, code := range codes {
+		if httpError.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound returns true when the error is a 404 (Not Found) HTTP error
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsAuthError returns true when the error is a 401 (Unauthorized) or a 403
+// (Forbidden) HTTP error
+func IsAuthError(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized, http.StatusForbidden)
+}
+
+// IsConflict returns true when the error is a 409 (Conflict) HTTP error
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
 // Taken from terraform-openstack-provider
 // CheckDeleted checks the error to see if it's a 404 (Not Found) and, if so,
 // sets the resource ID to the empty string instead of throwing an error.
 func CheckDeleted(d *schema.ResourceData, prefix string, err error) error {
-	if httpError, ok := err.(HTTPError); ok && httpError.StatusCode == 404 {
+	if IsNotFound(err) {
 		d.SetId("")
 		return nil
 	}
 
 	return fmt.Errorf("%s: %s", prefix, err)
 }
+
 func resolveURL(u *url.URL) (*url.URL, error) {
 	rand.Seed(time.Now().Unix()) // initialize global pseudo random generator
 	// Take a url, extract the hostname, resolve to canonical
@@ -61,3 +146,35 @@ func resolveURL(u *url.URL) (*url.URL, error) {
 	u.Host = newHost
 	return u, nil
 }
+
+// kerberosPrincipal returns the principal of the Kerberos credentials cache
+// used by the SPNEGO transport, looked up the same way go-spnego does
+func kerberosPrincipal() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	ccpath := "/tmp/krb5cc_" + u.Uid
+	ccname := os.Getenv("KRB5CCNAME")
+	if strings.HasPrefix(ccname, "FILE:") {
+		ccpath = strings.SplitN(ccname, ":", 2)[1]
+	}
+
+	ccache, err := credentials.LoadCCache(ccpath)
+	if err != nil {
+		return "", err
+	}
+
+	return ccache.DefaultPrincipal.PrincipalName.PrincipalNameString() + "@" + ccache.DefaultPrincipal.Realm, nil
+}
+
+// isPrincipal checks whether a user name as reported by the CERN services,
+// with or without realm, refers to the given principal
+func isPrincipal(name string, principal string) bool {
+	if strings.EqualFold(name, principal) {
+		return true
+	}
+	primary := strings.SplitN(principal, "@", 2)[0]
+	return strings.EqualFold(strings.SplitN(name, "@", 2)[0], primary)
+}
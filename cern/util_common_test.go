@@ -0,0 +1,63 @@
+package cern
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// newTestServer starts a stand-in of a CERN API served by handler. It returns
+// the URL and the client to reach it, and a function listing the requests it
+// received as "METHOD path".
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*url.URL, *http.Client, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serverURL, server.Client(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+// statusHandler answers every request with the given status and JSON body
+func statusHandler(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// checkRequests checks that the stand-in received exactly the given requests
+func checkRequests(t *testing.T, requests func() []string, expected ...string) {
+	t.Helper()
+
+	received := requests()
+	if len(received) != len(expected) {
+		t.Fatalf("expected requests %v, got %v", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Errorf("expected requests %v, got %v", expected, received)
+			return
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"time"
 )
 
 type Auth struct {
@@ -93,11 +95,12 @@ type soapEnvelope struct {
 }
 
 //NewLandbClient initialises a new connection to LanDB
-func NewLandbClient(endpoint string, username string, password string) (*LandbClient, error) {
+func NewLandbClient(ctx context.Context, endpoint string, username string, password string, httpClient *http.Client) (*LandbClient, error) {
 	client := LandbClient{
-		Endpoint: endpoint,
+		HTTPClient: httpClient,
+		Endpoint:   endpoint,
 	}
-	token, err := client.GetAuthToken(context.TODO(), username, password, "CERN")
+	token, err := client.GetAuthToken(ctx, username, password, "CERN")
 	if err != nil {
 		return nil, fmt.Errorf("Error requesting Landb auth token: %s", err)
 	}
@@ -107,6 +110,28 @@ func NewLandbClient(endpoint string, username string, password string) (*LandbCl
 	return &client, nil
 }
 
+// NewLandbHTTPClient builds the HTTP client used for the LanDB SOAP calls.
+// The timeout bounds every single request, including the time to read the
+// response body, so a hung call cannot block Terraform forever.
+func NewLandbHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
 func (c *LandbClient) do(ctx context.Context, method, action string, in, out interface{}) error {
 	var body io.Reader
 	var envelope soapEnvelope
@@ -123,15 +148,13 @@ func (c *LandbClient) do(ctx context.Context, method, action string, in, out int
 		}
 		body = &buf
 	}
-	req, err := http.NewRequest(method, c.Endpoint, body)
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint, body)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("SOAPAction", action)
 
-	req = req.WithContext(ctx)
-
 	if c.RequestHook != nil {
 		req = c.RequestHook(req)
 	}
@@ -311,3 +334,50 @@ func (c *LandbClient) VMRemoveCard(ctx context.Context, vmName string, hardwareA
 	err := c.do(ctx, "POST", "", &input, &output)
 	return bool(output.Result), err
 }
+
+// InterfaceInfo defines the subset of the LanDB interface information we use
+type InterfaceInfo struct {
+	Name        string `xml:"Name"`
+	IPAddress   string `xml:"IPAddress"`
+	IPv6Address string `xml:"IPv6Address"`
+}
+
+// DeviceInfo defines the subset of the LanDB device information we use
+type DeviceInfo struct {
+	DeviceName  string          `xml:"DeviceName"`
+	Description string          `xml:"Description"`
+	Tag         string          `xml:"Tag"`
+	Interfaces  []InterfaceInfo `xml:"Interfaces>item"`
+}
+
+// VMClusterGetDevices lists the names of the devices registered in a VM cluster
+func (c *LandbClient) VMClusterGetDevices(ctx context.Context, vmClusterName string) ([]string, error) {
+	var input struct {
+		XMLName       struct{} `xml:"urn:NetworkService vmClusterGetDevices"`
+		VMClusterName string   `xml:"urn:NetworkService VMClusterName"`
+	}
+	input.VMClusterName = string(vmClusterName)
+	var output struct {
+		XMLName struct{} `xml:"urn:NetworkService vmClusterGetDevicesResponse"`
+		Result  struct {
+			Items []string `xml:"item"`
+		} `xml:",any"`
+	}
+	err := c.do(ctx, "POST", "", &input, &output)
+	return output.Result.Items, err
+}
+
+// GetDeviceInfo gets the information of a device, including its interfaces
+func (c *LandbClient) GetDeviceInfo(ctx context.Context, deviceName string) (*DeviceInfo, error) {
+	var input struct {
+		XMLName    struct{} `xml:"urn:NetworkService getDeviceInfo"`
+		DeviceName string   `xml:"urn:NetworkService DeviceName"`
+	}
+	input.DeviceName = string(deviceName)
+	var output struct {
+		XMLName struct{}   `xml:"urn:NetworkService getDeviceInfoResponse"`
+		Result  DeviceInfo `xml:",any"`
+	}
+	err := c.do(ctx, "POST", "", &input, &output)
+	return &output.Result, err
+}
@@ -4,20 +4,46 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dpotapov/go-spnego"
 )
 
 // Roger client manages requests to the Roger service
 type Roger struct {
-	URL *url.URL
+	URL        *url.URL
+	HTTPClient *http.Client
+	AppStates  []string
+	Hosts      *HostNormalizer
+	// Retries is the number of times an idempotent request is retried on
+	// connection errors and 5xx responses
+	Retries int
 }
 
+// rogerRetryBackoff is the delay before the first retry, doubled after each
+// attempt
+const rogerRetryBackoff = 500 * time.Millisecond
+
+// DefaultRogerAppStates are the application states accepted by Roger, unless
+// overridden in the provider configuration
+var DefaultRogerAppStates = []string{
+	"build", "disabled", "draining", "hardware_repair", "intervention", "production", "test",
+}
+
+// LegacyRogerAppStates are still accepted by Roger but must not be used
+// anymore, they are superseded by 'intervention' and 'disabled'
+var LegacyRogerAppStates = []string{"maintenance", "standby"}
+
 type RogerRequest struct {
 	Hostname   string `json:"hostname"`
 	AppState   string `json:"appstate,omitempty"`
@@ -38,8 +64,68 @@ type RogerResponse struct {
 	UpdatedByPuppet bool   `json:"updated_by_puppet"`
 }
 
-// NewRogerClient constructs a new client configuration
-func NewRogerClient(endpoint string) (*Roger, error) {
+// boolValue dereferences the optional booleans of the Roger API, which are
+// false when missing
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// rogerExpiresLayout is the layout of the expiry dates sent to Roger, in UTC
+const rogerExpiresLayout = "2006-01-02 15:04:05"
+
+// rogerRelativeExpires matches the expiries relative to the time the state
+// is applied, like +2d
+var rogerRelativeExpires = regexp.MustCompile(`^\+([0-9]+)([mhdw])$`)
+
+var rogerExpiresUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// IsRelativeRogerExpires returns true when the expiry is relative to the
+// time the state is applied
+func IsRelativeRogerExpires(expires string) bool {
+	return rogerRelativeExpires.MatchString(expires)
+}
+
+// ParseRogerExpires parses an expiry given either relative to now, as
+// RFC3339, or in the Roger layout
+func ParseRogerExpires(expires string, now time.Time) (time.Time, error) {
+	if match := rogerRelativeExpires.FindStringSubmatch(expires); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(time.Duration(n) * rogerExpiresUnits[match[2]]).UTC(), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, rogerExpiresLayout, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, expires); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a RFC3339 date nor a relative duration like +2d", expires)
+}
+
+// NormalizeRogerExpires converts an expiry to the layout expected by Roger,
+// or returns it unchanged when it cannot be parsed
+func NormalizeRogerExpires(expires string, now time.Time) string {
+	if expires == "" {
+		return ""
+	}
+	t, err := ParseRogerExpires(expires, now)
+	if err != nil {
+		return expires
+	}
+	return t.Format(rogerExpiresLayout)
+}
+
+// NewRogerClient constructs a new client configuration, accepting the given
+// application states or the default ones when empty. The hostnames are
+// canonicalised with the given normaliser.
+func NewRogerClient(endpoint string, appStates []string, hosts *HostNormalizer) (*Roger, error) {
 	url, err := url.Parse(endpoint)
 
 	if err != nil {
@@ -51,11 +137,65 @@ func NewRogerClient(endpoint string) (*Roger, error) {
 		return nil, err
 	}
 
+	if len(appStates) == 0 {
+		appStates = DefaultRogerAppStates
+	}
+	var allowed []string
+	for _, appState := range appStates {
+		if _, legacy := find(LegacyRogerAppStates, appState); !legacy {
+			allowed = append(allowed, appState)
+		}
+	}
+
+	// The client is shared by every request so that the connections to
+	// Roger are reused, including by the concurrent bulk requests
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
 	return &Roger{
 		URL: url,
+		HTTPClient: &http.Client{
+			Transport: &spnego.Transport{
+				Transport: http.Transport{
+					Proxy:                 http.ProxyFromEnvironment,
+					DialContext:           dialer.DialContext,
+					MaxIdleConns:          32,
+					MaxIdleConnsPerHost:   32,
+					IdleConnTimeout:       90 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+		AppStates: allowed,
+		Hosts:     hosts,
+		Retries:   3,
 	}, nil
 }
 
+// CanonicalHostname returns the fully qualified name used by Roger for a host
+func (r Roger) CanonicalHostname(hostname string) string {
+	if r.Hosts == nil {
+		return hostname
+	}
+	return r.Hosts.Normalize(hostname)
+}
+
+// ValidateAppState returns an error when the application state is not one
+// accepted by Roger, or is a legacy one
+func (r Roger) ValidateAppState(appState string) error {
+	if _, legacy := find(LegacyRogerAppStates, appState); legacy {
+		return fmt.Errorf("appstate %q is a legacy one and must not be used anymore, expected one of %s",
+			appState, strings.Join(r.AppStates, ", "))
+	}
+	if _, found := find(r.AppStates, appState); !found {
+		return fmt.Errorf("appstate %q is not known by Roger, expected one of %s",
+			appState, strings.Join(r.AppStates, ", "))
+	}
+	return nil
+}
+
 // Get roger state for a given host
 func (r Roger) Get(ctx context.Context, hostname string) (*RogerResponse, error) {
 	rogerRequest := RogerRequest{
@@ -84,12 +224,31 @@ func (r Roger) Delete(ctx context.Context, hostname string) (*RogerResponse, err
 	return r.do(ctx, rogerRequest, "DELETE")
 }
 
-// Do a request to roger
-func (r Roger) do(ctx context.Context, rogerRequest RogerRequest, method string) (*RogerResponse, error) {
-	client := http.Client{
-		Transport: &spnego.Transport{},
+// List the roger states, restricted to the given appstate when set
+func (r Roger) List(ctx context.Context, appstate string) ([]RogerResponse, error) {
+	listURL := fmt.Sprintf("%s/roger/v1/state/", r.URL)
+	if appstate != "" {
+		listURL = fmt.Sprintf("%s?appstate=%s", listURL, url.QueryEscape(appstate))
 	}
+	log.Printf("[DEBUG] Request url constructed as follows: %s", listURL)
 
+	body, err := r.request(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rogerResponses []RogerResponse
+	err = json.Unmarshal(body, &rogerResponses)
+	if err != nil {
+		return nil, err
+	}
+
+	return rogerResponses, nil
+}
+
+// Do a request to roger
+func (r Roger) do(ctx context.Context, rogerRequest RogerRequest, method string) (*RogerResponse, error) {
+	rogerRequest.Hostname = r.CanonicalHostname(rogerRequest.Hostname)
 	url := fmt.Sprintf("%s/roger/v1/state/%s/", r.URL, rogerRequest.Hostname)
 	if method == "POST" {
 		url = fmt.Sprintf("%s/roger/v1/state/", r.URL)
@@ -102,38 +261,87 @@ func (r Roger) do(ctx context.Context, rogerRequest RogerRequest, method string)
 		log.Printf("[DEBUG] Request data: %s", string(requestData[:]))
 	}
 
+	body, err := r.request(ctx, method, url, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var rogerResponse RogerResponse
+	if method != "POST" && method != "DELETE" && method != "PUT" {
+		err = json.Unmarshal(body, &rogerResponse)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &rogerResponse, nil
+}
+
+// request sends a request to roger and returns the body of the response.
+// Only the idempotent requests are retried, so that a state is never created
+// twice.
+func (r Roger) request(ctx context.Context, method string, url string, requestData []byte) ([]byte, error) {
+	retries := r.Retries
+	if method == "POST" {
+		retries = 0
+	}
+
+	backoff := rogerRetryBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := r.requestOnce(ctx, method, url, requestData)
+		if err == nil || attempt >= retries || !isRetryableRogerError(err) {
+			return body, err
+		}
+
+		log.Printf("[WARN] Roger request to %s failed, retrying in %s: %s", url, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableRogerError returns true for the connection errors and the 5xx
+// responses
+func isRetryableRogerError(err error) bool {
+	var httpError HTTPError
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode >= 500
+	}
+	var spnegoError *spnego.Error
+	if errors.As(err, &spnegoError) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r Roger) requestOnce(ctx context.Context, method string, url string, requestData []byte) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestData))
 	if err != nil {
 		return nil, err
 	}
-	if method != "GET" && method != "DELETE" {
+	if requestData != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Accept-Encoding", "deflate")
 
-	resp, err := client.Do(req)
+	resp, err := r.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var rogerResponse RogerResponse
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode < 200 && resp.StatusCode >= 300 {
-		return nil, HTTPError{url, resp.StatusCode, string(body[:])}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newHTTPError(url, resp.StatusCode, body, "roger state not found")
 	}
 
-	if method != "POST" && method != "DELETE" && method != "PUT" {
-		err = json.Unmarshal(body, &rogerResponse)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return &rogerResponse, nil
+	return body, nil
 }
@@ -36,13 +36,94 @@ func SerializeHostgroup(hostgroup string) string {
 	return strings.ReplaceAll(hostgroup, "/", "-")
 }
 
+// HostgroupHierarchy returns the serialized hostgroups from the most specific
+// to the top level one, e.g. a/b/c gives a-b-c, a-b and a
+func HostgroupHierarchy(hostgroup string) []string {
+	parts := strings.Split(strings.Trim(hostgroup, "/"), "/")
+	hierarchy := make([]string, 0, len(parts))
+	for i := len(parts); i > 0; i-- {
+		hierarchy = append(hierarchy, strings.Join(parts[:i], "-"))
+	}
+	return hierarchy
+}
+
+// Host verification modes of the HostNormalizer
+const (
+	// HostVerificationAlways checks the host resolves before every request
+	HostVerificationAlways = "always"
+	// HostVerificationPlan only checks the hosts known at plan time
+	HostVerificationPlan = "plan"
+	// HostVerificationNever never checks the hosts
+	HostVerificationNever = "never"
+)
+
+// HostResolver resolves host names, net.Resolver implements it
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// HostNormalizer turns the host names given by the user into the FQDNs used
+// by Teigi, optionally verifying they exist in the DNS
+type HostNormalizer struct {
+	// DomainSuffix is appended to the short host names
+	DomainSuffix string
+	// Verification is one of HostVerificationAlways, HostVerificationPlan
+	// or HostVerificationNever
+	Verification string
+	Resolver     HostResolver
+}
+
+// NewHostNormalizer constructs a HostNormalizer using the default resolver
+func NewHostNormalizer(domainSuffix string, verification string) (*HostNormalizer, error) {
+	switch verification {
+	case HostVerificationAlways, HostVerificationPlan, HostVerificationNever:
+	default:
+		return nil, fmt.Errorf("unknown host verification mode '%s'", verification)
+	}
+
+	return &HostNormalizer{
+		DomainSuffix: strings.Trim(domainSuffix, "."),
+		Verification: verification,
+		Resolver:     net.DefaultResolver,
+	}, nil
+}
+
+// Normalize returns the FQDN of a host, without any DNS lookup
+func (h HostNormalizer) Normalize(host string) string {
+	fqdn := strings.ToLower(strings.TrimSuffix(host, "."))
+	if h.DomainSuffix != "" && !strings.Contains(fqdn, ".") {
+		fqdn = fqdn + "." + h.DomainSuffix
+	}
+	return fqdn
+}
+
+// Verify checks that the FQDN of a host resolves and is not an alias
+func (h HostNormalizer) Verify(ctx context.Context, host string) error {
+	fqdn := h.Normalize(host)
+	addrs, err := h.Resolver.LookupHost(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	// One IPv4 and one IPv6 address at most, more are likely a DNS alias
+	if len(addrs) == 0 {
+		return fmt.Errorf("fqdn '%s' does not resolve", fqdn)
+	} else if len(addrs) > 2 {
+		return fmt.Errorf("fqdn '%s' may be an alias", fqdn)
+	}
+
+	return nil
+}
+
 // Teigi client manages requests to the Teigi service
 type Teigi struct {
-	URL *url.URL
+	URL        *url.URL
+	Hosts      *HostNormalizer
+	HTTPClient *http.Client
 }
 
 // NewTeigiClient constructs a new client configuration
-func NewTeigiClient(endpoint string) (*Teigi, error) {
+func NewTeigiClient(endpoint string, hosts *HostNormalizer) (*Teigi, error) {
 	url, err := url.Parse(endpoint)
 
 	if err != nil {
@@ -55,28 +136,21 @@ func NewTeigiClient(endpoint string) (*Teigi, error) {
 	}
 
 	return &Teigi{
-		URL: url,
+		URL:   url,
+		Hosts: hosts,
+		HTTPClient: &http.Client{
+			Transport: &spnego.Transport{},
+		},
 	}, nil
 }
 
-func fqndify(host string) (string, error) {
-	fqdn := host
-	if strings.ContainsAny(fqdn, ".") {
-		fqdn = host + ".cern.ch"
+// newTeigiError builds an HTTPError out of a non 2xx Teigi response
+func newTeigiError(url string, statusCode int, body []byte) HTTPError {
+	httpError := newHTTPError(url, statusCode, body, "secret not found")
+	if IsConflict(httpError) && httpError.Message == "" {
+		httpError.Message = "secret already exists"
 	}
-
-	addrs, err := net.LookupHost(fqdn)
-	if err != nil {
-		return "", err
-	}
-
-	if len(addrs) == 0 {
-		return "", fmt.Errorf("fqdn '%s' does not resolve", fqdn)
-	} else if len(addrs) > 2 {
-		return "", fmt.Errorf("fqdn '%s' may be an alias", fqdn)
-	}
-
-	return fqdn, nil
+	return httpError
 }
 
 func (t Teigi) Create(ctx context.Context, scope string, entity, key string, secretRequest SecretRequest) error {
@@ -93,30 +167,76 @@ func (t Teigi) Get(ctx context.Context, scope string, entity, key string) (*Secr
 	return t.do(ctx, "GET", scope, entity, key, SecretRequest{})
 }
 
-// Get request
-func (t Teigi) do(ctx context.Context, method string, scope string, entity string, key string, secretRequest SecretRequest) (*SecretResponse, error) {
-	var err error
-	client := http.Client{
-		Transport: &spnego.Transport{},
+// List the keys of the secrets stored under an entity
+func (t Teigi) List(ctx context.Context, scope string, entity string) ([]string, error) {
+	url, err := t.secretsURL(ctx, scope, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := t.request(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
 
+	var keys []string
+	err = json.Unmarshal(body, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// secretsURL builds the url of the secrets stored under an entity
+func (t Teigi) secretsURL(ctx context.Context, scope string, entity string) (string, error) {
 	if scope == "host" {
-		entity, err = fqndify(entity)
-		if err != nil {
-			return nil, err
+		if t.Hosts.Verification == HostVerificationAlways {
+			if err := t.Hosts.Verify(ctx, entity); err != nil {
+				return "", err
+			}
 		}
+		entity = t.Hosts.Normalize(entity)
 	} else if scope == "hostgroup" {
 		entity = SerializeHostgroup(entity)
 	}
 
-	url := fmt.Sprintf("%s/tbag/v2/%s/%s/secret/%s/", t.URL, scope, entity, key)
-	log.Printf("[DEBUG] Request url constructed as follows: %s", url)
+	return fmt.Sprintf("%s/tbag/v2/%s/%s/secret/", t.URL, scope, entity), nil
+}
+
+// Get request
+func (t Teigi) do(ctx context.Context, method string, scope string, entity string, key string, secretRequest SecretRequest) (*SecretResponse, error) {
+	url, err := t.secretsURL(ctx, scope, entity)
+	if err != nil {
+		return nil, err
+	}
+	url = fmt.Sprintf("%s%s/", url, key)
 
 	var requestData []byte
 	if method == "POST" {
 		requestData, _ = json.Marshal(secretRequest)
 	}
 
+	body, err := t.request(ctx, method, url, requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretResponse SecretResponse
+	if method == "GET" {
+		err = json.Unmarshal(body, &secretResponse)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &secretResponse, nil
+}
+
+// request sends a request to Teigi and returns the body of the response
+func (t Teigi) request(ctx context.Context, method string, url string, requestData []byte) ([]byte, error) {
+	log.Printf("[DEBUG] Request url constructed as follows: %s", url)
+
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(requestData))
 	if err != nil {
 		return nil, err
@@ -124,7 +244,7 @@ func (t Teigi) do(ctx context.Context, method string, scope string, entity strin
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Accept-Encoding", "deflate")
 
-	resp, err := client.Do(req)
+	resp, err := t.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -135,17 +255,9 @@ func (t Teigi) do(ctx context.Context, method string, scope string, entity strin
 		return nil, err
 	}
 
-	if resp.StatusCode < 200 && resp.StatusCode >= 300 {
-		return nil, HTTPError{url, resp.StatusCode, string(body[:])}
-	}
-
-	var secretResponse SecretResponse
-	if method == "GET" {
-		err = json.Unmarshal(body, &secretResponse)
-		if err != nil {
-			return nil, err
-		}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newTeigiError(url, resp.StatusCode, body)
 	}
 
-	return &secretResponse, nil
+	return body, nil
 }
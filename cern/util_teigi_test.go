@@ -0,0 +1,168 @@
+package cern
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestTeigi returns a Teigi client talking to a stand-in of the Teigi API
+// served by handler
+func newTestTeigi(t *testing.T, handler http.HandlerFunc) (*Teigi, func() []string) {
+	t.Helper()
+
+	serverURL, client, requests := newTestServer(t, handler)
+	hosts, err := NewHostNormalizer("cern.ch", HostVerificationNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Teigi{
+		URL:        serverURL,
+		Hosts:      hosts,
+		HTTPClient: client,
+	}, requests
+}
+
+// teigiEntityHandler serves the secrets of a single Teigi entity out of the
+// given map, which is updated by the requests
+func teigiEntityHandler(secrets map[string]SecretRequest) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) == 5 && r.Method == "GET" {
+			keys := []string{}
+			for key := range secrets {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			json.NewEncoder(w).Encode(keys)
+			return
+		}
+
+		key := parts[len(parts)-1]
+		secret, exists := secrets[key]
+		switch {
+		case r.Method == "POST":
+			var request SecretRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			secrets[key] = request
+			w.WriteHeader(http.StatusCreated)
+		case !exists:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "DELETE":
+			delete(secrets, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			json.NewEncoder(w).Encode(SecretResponse{SecretRequest: secret, Skey: key})
+		}
+	}
+}
+
+func TestTeigiGet(t *testing.T) {
+	client, requests := newTestTeigi(t, statusHandler(http.StatusOK,
+		`{"secret": "s3cr3t", "encoding": "b64", "update_time": "1700000000", "updated_by": "someone"}`))
+
+	resp, err := client.Get(context.Background(), "host", "myhost", "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Secret != "s3cr3t" || resp.Encoding != "b64" || resp.UpdatedBy != "someone" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	checkRequests(t, requests, "GET /tbag/v2/host/myhost.cern.ch/secret/key/")
+}
+
+func TestTeigiErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		notFound bool
+		auth     bool
+		conflict bool
+		message  string
+	}{
+		{
+			name:    "forbidden",
+			status:  http.StatusForbidden,
+			body:    `{"message": "Permission denied"}`,
+			auth:    true,
+			message: "Permission denied, make sure a valid Kerberos ticket",
+		},
+		{
+			name:     "not found",
+			status:   http.StatusNotFound,
+			body:     `{"error": "No such key"}`,
+			notFound: true,
+			message:  "No such key",
+		},
+		{
+			name:     "not found without body",
+			status:   http.StatusNotFound,
+			notFound: true,
+			message:  "secret not found",
+		},
+		{
+			name:     "conflict",
+			status:   http.StatusConflict,
+			body:     `{"detail": "Secret already exists"}`,
+			conflict: true,
+			message:  "Secret already exists",
+		},
+		{
+			name:   "server error",
+			status: http.StatusInternalServerError,
+			body:   "<html>Internal Server Error</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestTeigi(t, statusHandler(tt.status, tt.body))
+
+			_, err := client.Get(context.Background(), "service", "myservice", "key")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			var httpError HTTPError
+			if !errors.As(err, &httpError) {
+				t.Fatalf("expected an HTTPError, got %T: %s", err, err)
+			}
+			if httpError.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, httpError.StatusCode)
+			}
+			if httpError.RespBody != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, httpError.RespBody)
+			}
+			if IsNotFound(err) != tt.notFound {
+				t.Errorf("IsNotFound() = %t, expected %t", IsNotFound(err), tt.notFound)
+			}
+			if IsAuthError(err) != tt.auth {
+				t.Errorf("IsAuthError() = %t, expected %t", IsAuthError(err), tt.auth)
+			}
+			if IsConflict(err) != tt.conflict {
+				t.Errorf("IsConflict() = %t, expected %t", IsConflict(err), tt.conflict)
+			}
+			if !strings.HasPrefix(httpError.Message, tt.message) {
+				t.Errorf("expected message starting with %q, got %q", tt.message, httpError.Message)
+			}
+			if tt.message != "" && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected %q in the error, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+terraform {
+  required_providers {
+    cern = {
+      source  = "cern-ops/cern"
+      version = "> 1.0.0"
+    }
+  }
+}
+
+variable "server_id" {
+  type = string
+}
+
+resource "cern_certmgr" "myhost" {
+  hostname     = "myhost.cern.ch"
+  renew_before = "720h"
+
+  triggers = {
+    server_id = var.server_id
+  }
+}
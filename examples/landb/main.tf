@@ -50,4 +50,14 @@ resource "cern_landb_vm_interface" "cloud_machine_interface" {
     service_name = "S513-C-VM2"
     address_type = "PUBLIC"
   }
-}
\ No newline at end of file
+}
+
+data "cern_landb_vm_cluster_orphans" "azure" {
+  vm_cluster_name = "XBATCH-LANDB-AZURE-VM-CLUSTER"
+  name_prefix     = "b7a99n"
+  managed_names   = [cern_landb_vm.cloud_machine.device_name]
+}
+
+output "azure_orphans" {
+  value = data.cern_landb_vm_cluster_orphans.azure.orphans
+}
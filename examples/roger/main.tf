@@ -0,0 +1,43 @@
+terraform {
+  required_providers {
+    cern = {
+      source  = "cern-ops/cern"
+      version = "> 1.0.0"
+    }
+  }
+}
+
+data "cern_roger" "web" {
+  hostname = "myhost.cern.ch"
+}
+
+output "web_in_production" {
+  value = data.cern_roger.web.appstate == "production"
+}
+
+data "cern_roger_states" "draining" {
+  appstate       = "draining"
+  hostname_regex = "^webfe[0-9]+\\.cern\\.ch$"
+}
+
+output "draining_hosts" {
+  value = data.cern_roger_states.draining.hostnames
+}
+
+# Drain the host during the intervention, destroying it puts the host back
+# in its previous state
+resource "cern_roger" "intervention" {
+  hostname           = "myhost.cern.ch"
+  appstate           = "draining"
+  message            = "Ceph upgrade"
+  expires            = "+2d"
+  restore_on_destroy = true
+}
+
+resource "cern_roger_bulk" "ceph_upgrade" {
+  hostnames   = data.cern_roger_states.draining.hostnames
+  appstate    = "intervention"
+  message     = "Ceph upgrade"
+  app_alarmed = false
+  parallelism = 20
+}
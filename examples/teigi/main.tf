@@ -15,3 +15,91 @@ data "cern_teigi_secret" "oops" {
 output "secret" {
   value = data.cern_teigi_secret.oops.secret
 }
+
+
+data "cern_teigi_secrets" "playground" {
+  hostgroup = "playground"
+}
+
+output "playground_keys" {
+  value = data.cern_teigi_secrets.playground.keys
+}
+
+resource "cern_teigi_generated_secret" "db_password" {
+  hostgroup = "playground"
+  key       = "db_password"
+  length    = 24
+  special   = false
+
+  keepers = {
+    rotation = "2022-05"
+  }
+}
+
+# Requires Terraform >= 1.10, the secret is never written to the plan or state
+ephemeral "cern_teigi_secret" "db_password" {
+  key       = "db_password"
+  hostgroup = "playground"
+}
+
+data "cern_teigi_secret" "puppet_view" {
+  key              = "test_this"
+  host             = "myhost.cern.ch"
+  hostgroup        = "playground/sub/leaf"
+  lookup_hierarchy = true
+}
+
+output "puppet_view_scope" {
+  value = "${data.cern_teigi_secret.puppet_view.matched_scope}/${data.cern_teigi_secret.puppet_view.matched_entity}"
+}
+
+resource "cern_teigi_secret_set" "playground" {
+  hostgroup       = "playground"
+  remove_unlisted = true
+
+  secrets = {
+    db_user   = "playground"
+    api_token = "not-so-secret"
+  }
+}
+
+resource "cern_teigi_secret" "keytab" {
+  hostgroup   = "playground"
+  key         = "service_keytab"
+  secret_file = "${path.module}/service.keytab"
+}
+
+# Promote the secret of the QA hostgroup to production
+resource "cern_teigi_secret_copy" "promote" {
+  source_hostgroup = "playground/qa"
+  source_key       = "api_token"
+  hostgroup        = "playground/production"
+  key              = "api_token"
+}
+
+data "cern_teigi_secret" "override" {
+  key           = "db_password_override"
+  hostgroup     = "playground"
+  allow_missing = true
+}
+
+data "cern_teigi_secret" "db" {
+  keys      = ["db_user", "db_password"]
+  hostgroup = "playground"
+}
+
+output "db_password" {
+  value     = data.cern_teigi_secret.override.exists ? data.cern_teigi_secret.override.secret : data.cern_teigi_secret.db.secrets["db_password"]
+  sensitive = true
+}
+
+data "cern_teigi_secret" "s3" {
+  key       = "s3_credentials"
+  hostgroup = "playground"
+  decode    = "json"
+}
+
+output "s3_access_key" {
+  value     = data.cern_teigi_secret.s3.decoded["access_key"]
+  sensitive = true
+}
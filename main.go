@@ -1,8 +1,13 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"context"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tf5server"
+	"github.com/hashicorp/terraform-plugin-mux/tf5muxserver"
 	"gitlab.cern.ch/ceph/terraform/terraform-provider-cern/cern"
 )
 
@@ -10,9 +15,23 @@ import (
 //go:generate go run github.com/hashicorp/terraform-plugin-docs/cmd/tfplugindocs
 
 func main() {
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: func() *schema.Provider {
-			return cern.Provider()
-		},
-	})
+	ctx := context.Background()
+
+	// The SDKv2 provider implements most of the resources, the framework
+	// one is needed for the features SDKv2 does not support, like ephemeral
+	// resources.
+	providers := []func() tfprotov5.ProviderServer{
+		cern.Provider().GRPCProvider,
+		providerserver.NewProtocol5(cern.NewFrameworkProvider()),
+	}
+
+	muxServer, err := tf5muxserver.NewMuxServer(ctx, providers...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = tf5server.Serve("registry.terraform.io/cern-ops/cern", muxServer.ProviderServer)
+	if err != nil {
+		log.Fatal(err)
+	}
 }